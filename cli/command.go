@@ -6,7 +6,9 @@ package cli
 
 import (
 	"github.com/spf13/cobra"
+	"kerosenelabs.com/espresso/core/context/project"
 	"kerosenelabs.com/espresso/core/service"
+	"kerosenelabs.com/espresso/core/util"
 )
 
 // GetVersionCommand gets the prepared "version" command for cobra
@@ -42,9 +44,56 @@ func GetBuildCommand() *cobra.Command {
 		Short:   "Build the project, outputting a distributable.",
 		Aliases: []string{"b"},
 		Run: func(cmd *cobra.Command, args []string) {
+			var vendor, _ = cmd.Flags().GetBool("vendor")
+			if cmd.Flags().Changed("vendor") {
+				util.SetVendorMode(vendor)
+			}
 			service.BuildProject()
 		},
 	}
+	root.Flags().Bool("vendor", false, "Resolve dependencies only from the vendor directory (or set ESPRESSO_VENDOR=1)")
+	return root
+}
+
+// GetDistCommand gets the prepared "dist" command for cobra
+func GetDistCommand() *cobra.Command {
+	var root = &cobra.Command{
+		Use:   "dist",
+		Short: "Package the built application into .tar.gz and .zip distributions with launcher scripts.",
+		Run: func(cmd *cobra.Command, args []string) {
+			service.PackageDistribution()
+		},
+	}
+	return root
+}
+
+// GetJlinkCommand gets the prepared "jlink" command for cobra
+func GetJlinkCommand() *cobra.Command {
+	var root = &cobra.Command{
+		Use:   "jlink",
+		Short: "Create a runtime image of only the JDK modules the built application needs, in dist/runtime.",
+		Run: func(cmd *cobra.Command, args []string) {
+			var compress, _ = cmd.Flags().GetString("compress")
+			var stripDebug, _ = cmd.Flags().GetBool("strip-debug")
+			var modules, _ = cmd.Flags().GetStringSlice("add-modules")
+			service.LinkRuntime(project.JlinkConfig{Compress: compress, StripDebug: stripDebug, Modules: modules})
+		},
+	}
+	root.Flags().String("compress", "", "Compression passed to jlink's --compress (ex: zip-6), overriding jlink.compress")
+	root.Flags().Bool("strip-debug", false, "Strip debug information from the runtime image")
+	root.Flags().StringSlice("add-modules", nil, "Modules to include in addition to those found by jdeps")
+	return root
+}
+
+// GetVendorCommand gets the prepared "vendor" command for cobra
+func GetVendorCommand() *cobra.Command {
+	var root = &cobra.Command{
+		Use:   "vendor",
+		Short: "Copy every dependency package into the project's vendor directory.",
+		Run: func(cmd *cobra.Command, args []string) {
+			service.VendorDependencies()
+		},
+	}
 	return root
 }
 
@@ -80,13 +129,37 @@ func GetRegistryCommand() *cobra.Command {
 		Aliases: []string{"q"},
 		Run: func(cmd *cobra.Command, args []string) {
 			var term, _ = cmd.Flags().GetString("term")
-			service.QueryRegistries(term)
+			var group, _ = cmd.Flags().GetString("group")
+			var reg, _ = cmd.Flags().GetString("registry")
+			var processor, _ = cmd.Flags().GetBool("processor")
+			var output, _ = cmd.Flags().GetString("output")
+			service.QueryRegistries(term, service.QueryOptions{
+				Group:     group,
+				Registry:  reg,
+				Processor: processor,
+				Output:    output,
+			})
 		},
 	}
-	query.Flags().StringP("term", "t", "", "Term to query by")
-	query.MarkFlagRequired("term")
+	query.Flags().StringP("term", "t", "*", "Term to query by, fuzzily matched against the group, name and description")
+	query.Flags().StringP("group", "g", "", "Only include packages within this group")
+	query.Flags().StringP("registry", "r", "", "Only include packages from this registry")
+	query.Flags().Bool("processor", false, "Only include annotation processors")
+	query.Flags().StringP("output", "o", "table", "Output format (table or json)")
 	root.AddCommand(query)
 
+	var show = &cobra.Command{
+		Use:   "show <group:name>",
+		Short: "Show every version of a package and the registry that resolves it.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var output, _ = cmd.Flags().GetString("output")
+			service.ShowPackage(args[0], output)
+		},
+	}
+	show.Flags().StringP("output", "o", "table", "Output format (table or json)")
+	root.AddCommand(show)
+
 	var pull = &cobra.Command{
 		Use:   "invalidate",
 		Short: "Invalidate and recache the declared registries.",
@@ -95,6 +168,57 @@ func GetRegistryCommand() *cobra.Command {
 		},
 	}
 	root.AddCommand(pull)
+
+	var publish = &cobra.Command{
+		Use:   "publish",
+		Short: "Write a jar as a package version into a local registry checkout.",
+		Run: func(cmd *cobra.Command, args []string) {
+			var registryPath, _ = cmd.Flags().GetString("registry")
+			var fromProject, _ = cmd.Flags().GetBool("project")
+			var group, _ = cmd.Flags().GetString("group")
+			var name, _ = cmd.Flags().GetString("name")
+			var version, _ = cmd.Flags().GetString("version")
+			var jar, _ = cmd.Flags().GetString("jar")
+			var artifactUrl, _ = cmd.Flags().GetString("artifact-url")
+			var dependencies, _ = cmd.Flags().GetStringSlice("dependency")
+			var isAnnotationProcessor, _ = cmd.Flags().GetBool("annotation-processor")
+			var description, _ = cmd.Flags().GetString("description")
+
+			if fromProject {
+				service.PublishProject(registryPath, group, name, artifactUrl, description)
+			} else {
+				service.PublishPackage(registryPath, group, name, version, jar, artifactUrl, dependencies, isAnnotationProcessor, description)
+			}
+		},
+	}
+	publish.Flags().StringP("registry", "r", ".", "Path to the local registry checkout")
+	publish.Flags().Bool("project", false, "Publish the current project's distributable")
+	publish.Flags().StringP("group", "g", "", "Group of the package")
+	publish.Flags().StringP("name", "n", "", "Name of the package")
+	publish.Flags().StringP("version", "v", "", "Version number to publish")
+	publish.Flags().StringP("jar", "j", "", "Path to the jar to publish")
+	publish.Flags().StringP("artifact-url", "u", "", "Url the jar will be downloaded from")
+	publish.Flags().StringSliceP("dependency", "d", []string{}, "Transient dependency in the group:name:version format (repeatable)")
+	publish.Flags().Bool("annotation-processor", false, "Mark the package as an annotation processor")
+	publish.Flags().String("description", "", "Description of the package")
+	publish.MarkFlagRequired("artifact-url")
+	publish.MarkFlagsOneRequired("jar", "project")
+	publish.MarkFlagsMutuallyExclusive("jar", "project")
+	root.AddCommand(publish)
+
+	var lint = &cobra.Command{
+		Use:   "lint <path>",
+		Short: "Validate the package declarations within a local registry checkout.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var output, _ = cmd.Flags().GetString("output")
+			var requireChecksums, _ = cmd.Flags().GetBool("require-checksums")
+			service.LintRegistry(args[0], output, requireChecksums)
+		},
+	}
+	lint.Flags().StringP("output", "o", "text", "Output format (text or json)")
+	lint.Flags().Bool("require-checksums", false, "Treat versions without a checksum as errors")
+	root.AddCommand(lint)
 	return root
 }
 
@@ -115,5 +239,158 @@ func GetDependencyCommand() *cobra.Command {
 	}
 	root.AddCommand(sync)
 
+	var add = &cobra.Command{
+		Use:   "add <group:name[:version]>",
+		Short: "Add a dependency, using the latest version within the registries if no version is given.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			service.AddDependency(args[0])
+		},
+	}
+	root.AddCommand(add)
+
+	var remove = &cobra.Command{
+		Use:     "remove <group:name>",
+		Short:   "Remove a dependency.",
+		Aliases: []string{"rm"},
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			service.RemoveDependency(args[0])
+		},
+	}
+	root.AddCommand(remove)
+
+	var upgrade = &cobra.Command{
+		Use:   "upgrade [group:name[:version]]...",
+		Short: "Upgrade the given dependencies (or all of them) to the given version, or the latest within the registries.",
+		Run: func(cmd *cobra.Command, args []string) {
+			service.UpgradeDependencies(args)
+		},
+	}
+	root.AddCommand(upgrade)
+
+	var tree = &cobra.Command{
+		Use:   "tree",
+		Short: "Print the resolved dependency graph.",
+		Run: func(cmd *cobra.Command, args []string) {
+			var output, _ = cmd.Flags().GetString("output")
+			service.PrintDependencyTree(output)
+		},
+	}
+	tree.Flags().StringP("output", "o", "tree", "Output format (tree, json or dot)")
+	root.AddCommand(tree)
+
+	var why = &cobra.Command{
+		Use:   "why <group:name>",
+		Short: "Print every path from the project's direct dependencies to the given package.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var output, _ = cmd.Flags().GetString("output")
+			service.PrintDependencyPaths(args[0], output)
+		},
+	}
+	why.Flags().StringP("output", "o", "tree", "Output format (tree, json or dot)")
+	root.AddCommand(why)
+
+	var outdated = &cobra.Command{
+		Use:   "outdated",
+		Short: "Report dependencies with newer versions available within the registries.",
+		Run: func(cmd *cobra.Command, args []string) {
+			var output, _ = cmd.Flags().GetString("output")
+			service.ReportOutdatedDependencies(output)
+		},
+	}
+	outdated.Flags().StringP("output", "o", "table", "Output format (table or json)")
+	root.AddCommand(outdated)
+
+	return root
+}
+
+// GetCacheCommand gets the prepared "cache" command for cobra
+func GetCacheCommand() *cobra.Command {
+	var root = &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the package cache shared by all projects.",
+	}
+
+	var list = &cobra.Command{
+		Use:     "list",
+		Short:   "List every cached package.",
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var output, _ = cmd.Flags().GetString("output")
+			service.ListCache(output)
+		},
+	}
+	list.Flags().StringP("output", "o", "table", "Output format (table or json)")
+	root.AddCommand(list)
+
+	var verify = &cobra.Command{
+		Use:   "verify",
+		Short: "Recompute the checksum of every cached package against the one recorded when it was cached.",
+		Run: func(cmd *cobra.Command, args []string) {
+			var remove, _ = cmd.Flags().GetBool("remove")
+			service.VerifyCache(remove)
+		},
+	}
+	verify.Flags().Bool("remove", false, "Remove packages that fail verification")
+	root.AddCommand(verify)
+
+	var prune = &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cached packages not referenced by the given projects or not used within the given days.",
+		Run: func(cmd *cobra.Command, args []string) {
+			var projects, _ = cmd.Flags().GetStringSlice("project")
+			var days, _ = cmd.Flags().GetInt("days")
+			var dryRun, _ = cmd.Flags().GetBool("dry-run")
+			service.PruneCache(projects, days, dryRun)
+		},
+	}
+	prune.Flags().StringSliceP("project", "p", []string{}, "Project (directory or espresso.yml) whose packages are kept (repeatable)")
+	prune.Flags().IntP("days", "d", 0, "Remove packages not used within this many days")
+	prune.Flags().Bool("dry-run", false, "Print what would be pruned without removing anything")
+	root.AddCommand(prune)
+
+	var clear = &cobra.Command{
+		Use:   "clear",
+		Short: "Remove every cached package.",
+		Run: func(cmd *cobra.Command, args []string) {
+			service.ClearCache()
+		},
+	}
+	root.AddCommand(clear)
+
+	return root
+}
+
+// GetToolchainCommand gets the prepared "toolchain" command for cobra
+func GetToolchainCommand() *cobra.Command {
+	var root = &cobra.Command{
+		Use:   "toolchain",
+		Short: "Manage the JDKs on this machine that projects may require.",
+	}
+
+	var list = &cobra.Command{
+		Use:     "list",
+		Short:   "Discover and list the JDKs within JAVA_HOME, /usr/lib/jvm, SDKMAN and asdf.",
+		Aliases: []string{"ls"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var output, _ = cmd.Flags().GetString("output")
+			service.ListToolchains(output)
+		},
+	}
+	list.Flags().StringP("output", "o", "table", "Output format (table or json)")
+	root.AddCommand(list)
+
+	var add = &cobra.Command{
+		Use:   "add <path>",
+		Short: "Add a JDK installed outside the discovered locations.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			service.AddToolchain(args[0])
+		},
+	}
+	root.AddCommand(add)
+
 	return root
 }
@@ -5,27 +5,88 @@
 package project
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 
 	"gopkg.in/yaml.v3"
 )
 
-// Dependency represents a particular dependency
+// Dependency represents a particular dependency. A dependency with a Path refers to a local jar, or a directory of
+// jars, relative to the project rather than a package within a registry. A local jar pins its Checksum, a directory
+// the Checksums of its jars by file name, both recorded by "dependency sync". Exclusions are "group:name" packages
+// dropped from anywhere beneath this dependency in the graph.
 type Dependency struct {
+	Group      string            `yaml:"group,omitempty"`
+	Name       string            `yaml:"name,omitempty"`
+	Version    Version           `yaml:"version,omitempty"`
+	Path       string            `yaml:"path,omitempty"`
+	Checksum   string            `yaml:"checksum,omitempty"`
+	Checksums  map[string]string `yaml:"checksums,omitempty"`
+	Exclusions []string          `yaml:"exclusions,omitempty"`
+}
+
+// Resources configures how src/resources is copied into the build. Filter lists the glob patterns (relative to
+// src/resources, ex: "**/*.yml") of the files that have their ${...} placeholders substituted.
+type Resources struct {
+	Filter []string `yaml:"filter,omitempty"`
+}
+
+// ManifestConfig customizes the manifest of the project's jar. Attributes are added to its main section (ex:
+// Add-Opens), Sections to per-entry sections, and MultiRelease marks it as a multi-release jar.
+type ManifestConfig struct {
+	Attributes   map[string]string `yaml:"attributes,omitempty"`
+	Sections     []ManifestSection `yaml:"sections,omitempty"`
+	MultiRelease bool              `yaml:"multiRelease,omitempty"`
+}
+
+// ManifestSection is a named per-entry section of the manifest (ex: a package directory, "org/example/")
+type ManifestSection struct {
+	Name       string            `yaml:"name"`
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+// JlinkConfig configures the runtime image created by "espresso jlink". Compress is passed to jlink's --compress (ex:
+// "zip-6", or "2" on older JDKs), StripDebug strips debug information and Modules are added to those found by jdeps
+// (ex: modules only loaded reflectively, such as jdk.crypto.ec).
+type JlinkConfig struct {
+	Compress   string   `yaml:"compress,omitempty"`
+	StripDebug bool     `yaml:"stripDebug,omitempty"`
+	Modules    []string `yaml:"modules,omitempty"`
+}
+
+// DistributionConfig configures the archives created by "espresso dist". ConfDir is the directory, relative to the
+// project, copied into the archives' conf directory, defaulting to src/conf when it exists.
+type DistributionConfig struct {
+	ConfDir string `yaml:"confDir,omitempty"`
+}
+
+// Override forces a version of a package wherever it appears within the dependency graph
+type Override struct {
 	Group   string  `yaml:"group"`
 	Name    string  `yaml:"name"`
 	Version Version `yaml:"version"`
 }
 
+// IsLocal returns if the dependency refers to a local jar or directory of jars
+func (dep Dependency) IsLocal() bool {
+	return dep.Path != ""
+}
+
 // Registry represents a particular repository
 type Registry struct {
 	Name string `yaml:"name"`
 	Url  string `yaml:"url"`
+	Type string `yaml:"type,omitempty"`
 }
 
-// Toolchain represents the toolchain on the system
+// Toolchain is the JDK the project requires, by its feature Version (ex: 21) and optionally its Vendor (ex: "temurin").
+// The matching JDK is discovered on each machine, see "espresso toolchain list". Path is only honored when no Version
+// is set, for projects initialized before toolchains were declared by requirement.
 type Toolchain struct {
-	Path string `yaml:"path"`
+	Version int    `yaml:"version,omitempty"`
+	Vendor  string `yaml:"vendor,omitempty"`
+	Path    string `yaml:"path,omitempty"`
 }
 
 // ProjectVersion represents a semantic version number
@@ -38,12 +99,50 @@ type Version struct {
 
 // ProjectConfig represents an Espresso project
 type ProjectConfig struct {
-	Name         string       `yaml:"name"`
-	Version      Version      `yaml:"version"`
-	BasePackage  string       `yaml:"basePackage"`
-	Toolchain    Toolchain    `yaml:"toolchain"`
-	Dependencies []Dependency `yaml:"dependencies"`
-	Registries   []Registry   `yaml:"registries"`
+	Name        string  `yaml:"name"`
+	Version     Version `yaml:"version"`
+	BasePackage string  `yaml:"basePackage"`
+	// Type is either "application" (the default) or "library"
+	Type string `yaml:"type,omitempty"`
+	// SourceDirs are the source roots compiled into the project, relative to it, defaulting to src/java
+	SourceDirs []string `yaml:"sourceDirs,omitempty"`
+	// GeneratedSourceDirs are additional source roots written by code generators, they may not exist yet
+	GeneratedSourceDirs []string `yaml:"generatedSourceDirs,omitempty"`
+	// MainClass is the fully qualified entry point of an application, detected from the compiled classes if omitted
+	MainClass    string             `yaml:"mainClass,omitempty"`
+	Toolchain    Toolchain          `yaml:"toolchain"`
+	Dependencies []Dependency       `yaml:"dependencies"`
+	Overrides    []Override         `yaml:"overrides,omitempty"`
+	Registries   []Registry         `yaml:"registries"`
+	LocalCache   bool               `yaml:"localCache,omitempty"`
+	Resources    Resources          `yaml:"resources,omitempty"`
+	Manifest     ManifestConfig     `yaml:"manifest,omitempty"`
+	Jlink        JlinkConfig        `yaml:"jlink,omitempty"`
+	Distribution DistributionConfig `yaml:"distribution,omitempty"`
+	// Properties are substituted for ${...} placeholders within filtered resources
+	Properties map[string]string `yaml:"properties,omitempty"`
+}
+
+// ProjectTypeApplication and ProjectTypeLibrary are the types of project
+const (
+	ProjectTypeApplication = "application"
+	ProjectTypeLibrary     = "library"
+)
+
+// IsLibrary returns if the project is a library, which has no entry point
+func (cfg ProjectConfig) IsLibrary() bool {
+	return cfg.Type == ProjectTypeLibrary
+}
+
+// DefaultSourceDir is the source root of projects that don't configure their own
+const DefaultSourceDir = "src/java"
+
+// GetSourceDirs gets the project's configured source roots, or the default one
+func (cfg ProjectConfig) GetSourceDirs() []string {
+	if len(cfg.SourceDirs) == 0 {
+		return []string{DefaultSourceDir}
+	}
+	return cfg.SourceDirs
 }
 
 // UnmarshalConfig marshals the given ProjectConfig to yml
@@ -66,14 +165,130 @@ func MarshalConfig(cfg ProjectConfig) (*string, error) {
 	return &resp, nil
 }
 
-// readConfigFromFileSystem reads and parses the config from the filesystem.
-func readConfigFromFileSystem() (ProjectConfig, error) {
-	// get the config path
-	configPath, err := GetConfigPath()
+// MarshalConfigPreserving marshals the given ProjectConfig to yml on top of the existing yml document, keeping the
+// existing document's comments, key ordering and formatting wherever the values are unchanged.
+func MarshalConfigPreserving(cfg ProjectConfig, existing []byte) (*string, error) {
+	if len(bytes.TrimSpace(existing)) == 0 {
+		return MarshalConfig(cfg)
+	}
+
+	// parse both documents
+	var existingDoc yaml.Node
+	err := yaml.Unmarshal(existing, &existingDoc)
 	if err != nil {
-		return ProjectConfig{}, err
+		return nil, err
+	}
+	var updated yaml.Node
+	err = updated.Encode(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if existingDoc.Kind != yaml.DocumentNode || len(existingDoc.Content) == 0 {
+		return MarshalConfig(cfg)
+	}
+
+	// merge the updated values into the existing document
+	mergeYamlNode(existingDoc.Content[0], &updated)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(4)
+	err = encoder.Encode(&existingDoc)
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp := buf.String()
+	return &resp, nil
+}
+
+// mergeYamlNode updates the existing node in place to hold the updated node's values while keeping the existing
+// node's comments. Mapping keys keep their existing order with new keys appended, and sequences of mappings are
+// matched up by their "group" and "name" keys so comments follow the item they belong to.
+func mergeYamlNode(existing *yaml.Node, updated *yaml.Node) {
+	if existing.Kind != updated.Kind {
+		head, line, foot := existing.HeadComment, existing.LineComment, existing.FootComment
+		*existing = *updated
+		existing.HeadComment, existing.LineComment, existing.FootComment = head, line, foot
+		return
+	}
+
+	switch existing.Kind {
+	case yaml.ScalarNode:
+		if existing.Value != updated.Value || existing.Tag != updated.Tag {
+			existing.Value = updated.Value
+			existing.Tag = updated.Tag
+			existing.Style = updated.Style
+		}
+	case yaml.MappingNode:
+		content := []*yaml.Node{}
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			key, value := updated.Content[i], updated.Content[i+1]
+			if existingValue := findYamlMappingValue(existing, key.Value); existingValue != nil {
+				mergeYamlNode(existingValue, value)
+			}
+		}
+		// keep the existing order of keys that are still present, then add the new ones
+		for i := 0; i+1 < len(existing.Content); i += 2 {
+			if findYamlMappingValue(updated, existing.Content[i].Value) != nil {
+				content = append(content, existing.Content[i], existing.Content[i+1])
+			}
+		}
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			if findYamlMappingValue(existing, updated.Content[i].Value) == nil {
+				content = append(content, updated.Content[i], updated.Content[i+1])
+			}
+		}
+		existing.Content = content
+	case yaml.SequenceNode:
+		existingByIdentity := map[string]*yaml.Node{}
+		for i, item := range existing.Content {
+			existingByIdentity[getYamlItemIdentity(item, i)] = item
+		}
+		content := []*yaml.Node{}
+		for i, item := range updated.Content {
+			if existingItem, ok := existingByIdentity[getYamlItemIdentity(item, i)]; ok {
+				mergeYamlNode(existingItem, item)
+				content = append(content, existingItem)
+			} else {
+				content = append(content, item)
+			}
+		}
+		existing.Content = content
+		existing.Style = updated.Style
 	}
+}
+
+// findYamlMappingValue finds the value of the given key within a mapping node
+func findYamlMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
 
+// getYamlItemIdentity gets what identifies a sequence item, its "group" and "name" if it's a mapping, otherwise its index
+func getYamlItemIdentity(item *yaml.Node, index int) string {
+	if item.Kind == yaml.MappingNode {
+		group, name := findYamlMappingValue(item, "group"), findYamlMappingValue(item, "name")
+		if name != nil {
+			identity := "name:" + name.Value
+			if group != nil {
+				identity = "group:" + group.Value + ":" + identity
+			}
+			return identity
+		}
+	}
+	return fmt.Sprintf("index:%d", index)
+}
+
+// ReadConfig reads and parses the config at the given path.
+func ReadConfig(configPath string) (ProjectConfig, error) {
 	// read the file
 	configYml, err := os.ReadFile(configPath)
 	if err != nil {
@@ -88,3 +303,13 @@ func readConfigFromFileSystem() (ProjectConfig, error) {
 
 	return projectConfig, nil
 }
+
+// readConfigFromFileSystem reads and parses the config from the filesystem.
+func readConfigFromFileSystem() (ProjectConfig, error) {
+	// get the config path
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return ProjectConfig{}, err
+	}
+	return ReadConfig(configPath)
+}
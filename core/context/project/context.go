@@ -1,6 +1,11 @@
 package project
 
-import "sync"
+import (
+	"path/filepath"
+	"sync"
+
+	"kerosenelabs.com/espresso/core/util"
+)
 
 // ProjectContext provides context for this instances current project (if any)
 type ProjectContext struct {
@@ -30,6 +35,11 @@ func GetProjectContext() (*ProjectContext, error) {
 			return
 		}
 
+		// hermetic projects keep their caches next to their config
+		if config.LocalCache || util.IsLocalCacheMode() {
+			util.SetProjectCachePath(filepath.Join(filepath.Dir(configPath), ".espresso"))
+		}
+
 		// set the project context
 		projectContext = &ProjectContext{Config: config, ConfigPath: configPath}
 	})
@@ -1,8 +1,8 @@
 package project
 
 import (
-	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"kerosenelabs.com/espresso/core/util"
@@ -24,6 +24,15 @@ func GetConfigPath() (string, error) {
 	}
 }
 
+// GetProjectPath gets the absolute path to the directory containing the config file
+func GetProjectPath() (string, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(configPath), nil
+}
+
 // GetSourcePath gets the path at which there should be source files
 func GetSourcePath(projectConfig ProjectConfig) (string, error) {
 	wd, err := os.Getwd()
@@ -40,42 +49,52 @@ func GetSourcePath(projectConfig ProjectConfig) (string, error) {
 	return path, nil
 }
 
-// Persist persists the configuration under the EnvironmentContext to the filesystem
+// Persist persists the configuration under the EnvironmentContext to the filesystem. If the config already exists, the
+// changes are merged into it so its comments and key ordering are preserved.
 func Persist(projectContext ProjectContext) error {
-	marshalData, err := MarshalConfig(projectContext.Config)
-	if err != nil {
-		return err
-	}
-
-	// write the config
+	// read the existing config, if any
 	cfgExists, err := util.DoesPathExist(projectContext.ConfigPath)
 	if err != nil {
 		return err
 	}
-
+	var existing []byte
 	if cfgExists {
-		file, err := os.Open(projectContext.ConfigPath)
+		existing, err = os.ReadFile(projectContext.ConfigPath)
 		if err != nil {
 			return err
 		}
-		file.WriteString(*marshalData)
-		defer file.Close()
+	}
 
-	} else {
-		file, err := os.Create(projectContext.ConfigPath)
-		if err != nil {
-			return err
-		}
-		file.WriteString(*marshalData)
-		defer file.Close()
+	// marshal our config on top of it
+	marshalData, err := MarshalConfigPreserving(projectContext.Config, existing)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	// write to a temp file and move it into place so we never leave a partially written config behind
+	tmp, err := os.CreateTemp(filepath.Dir(projectContext.ConfigPath), ".espresso.yml.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(*marshalData)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), projectContext.ConfigPath)
 }
 
 // WriteExampleCode is an internal function that writes example code to a newly created project
 func WriteExampleCode(projectContext ProjectContext) error {
-	fmt.Printf("%s", projectContext)
 	err := os.MkdirAll(projectContext.SourcePath, 0755)
 	if err != nil {
 		return err
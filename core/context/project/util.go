@@ -1,6 +1,10 @@
 package project
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // GetVersionAsString gets a project version as a string.
 func GetVersionAsString(version Version) string {
@@ -10,3 +14,43 @@ func GetVersionAsString(version Version) string {
 	}
 	return versionString
 }
+
+// ParseVersion parses a version number string (ex: "1.2.3", "2.0", "1.2.3-beta1") into a Version. Missing minor and
+// patch numbers default to zero and anything after them is kept as the hotfix.
+func ParseVersion(number string) (Version, error) {
+	// collect up to three leading numeric components
+	rest := number
+	components := []int64{}
+	for len(components) < 3 {
+		end := 0
+		for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		value, err := strconv.ParseInt(rest[:end], 10, 64)
+		if err != nil {
+			return Version{}, err
+		}
+		components = append(components, value)
+		rest = rest[end:]
+		if len(components) < 3 && strings.HasPrefix(rest, ".") && len(rest) > 1 && rest[1] >= '0' && rest[1] <= '9' {
+			rest = rest[1:]
+			continue
+		}
+		break
+	}
+	if len(components) == 0 {
+		return Version{}, fmt.Errorf("'%s' is not a valid version number", number)
+	}
+	for len(components) < 3 {
+		components = append(components, 0)
+	}
+
+	version := Version{Major: components[0], Minor: components[1], Patch: components[2]}
+	if rest != "" {
+		version.Hotfix = &rest
+	}
+	return version, nil
+}
@@ -5,21 +5,61 @@
 package dependency
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"kerosenelabs.com/espresso/core/util"
 )
 
+// CacheEntryMetadata is the file format of the metadata stored next to each cached package (<signature>.json),
+// mapping the package's opaque signature back to where it came from.
+type CacheEntryMetadata struct {
+	Registry    string    `json:"registry"`
+	Group       string    `json:"group"`
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	ArtifactUrl string    `json:"artifactUrl"`
+	Checksum    string    `json:"checksum"`
+	CachedAt    time.Time `json:"cachedAt"`
+	LastUsed    time.Time `json:"lastUsed"`
+}
+
+// CacheEntry is a package within the package cache
+type CacheEntry struct {
+	Signature string
+	Path      string
+	Size      int64
+	// Metadata is nil for packages cached before metadata was recorded
+	Metadata *CacheEntryMetadata
+}
+
+// GetPackageCachePath gets the path of the directory holding all cached packages
+func GetPackageCachePath() (string, error) {
+	espressoPath, err := util.GetEspressoCachePath()
+	if err != nil {
+		return "", err
+	}
+	return espressoPath + "/cachedPackages", nil
+}
+
 // CacheResolvedDependency fetches the resolved dependency from the internet
 func CacheResolvedDependency(resolvedDependency ResolvedDependency) error {
-	// get where we should store this package
-	espressoPath, err := util.GetEspressoDirectoryPath()
-	if err != nil {
-		return err
+	// local jars are used in place, they only need to still be there
+	if resolvedDependency.IsLocal() {
+		return util.VerifyFileChecksum(resolvedDependency.LocalPath, resolvedDependency.PackageVersion.Checksum)
 	}
 
 	// ensure the package path exists
-	err = os.MkdirAll(espressoPath+"/cachedPackages", 0755)
+	cachePath, err := GetPackageCachePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(cachePath, 0755)
 	if err != nil {
 		return err
 	}
@@ -30,10 +70,294 @@ func CacheResolvedDependency(resolvedDependency ResolvedDependency) error {
 		return err
 	}
 
-	// download the file
-	err = util.DownloadFile(pkgPath.Absolute, resolvedDependency.PackageVersion.ArtifactUrl)
+	// only one process may download a package at once, the rest wait and then find it cached
+	lock, err := lockCacheEntry(pkgPath.Absolute, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	complete, err := ensureCacheEntryComplete(resolvedDependency, pkgPath.Absolute)
+	if err != nil || complete {
+		return err
+	}
+
+	// download the file, verifying it against the registry's checksum if it has one
+	opts := util.DefaultDownloadOptions
+	opts.Checksum = resolvedDependency.PackageVersion.Checksum
+	err = util.DownloadFileWithOptions(pkgPath.Absolute, resolvedDependency.PackageVersion.ArtifactUrl, opts)
+	if err != nil {
+		return err
+	}
+
+	return writeResolvedDependencyMetadata(resolvedDependency, pkgPath.Absolute)
+}
+
+// IsResolvedDependencyCached returns if the resolved dependency is within the package cache along with its metadata,
+// so it's available offline. Local jars only need to still be there.
+func IsResolvedDependencyCached(resolvedDependency ResolvedDependency) (bool, error) {
+	if resolvedDependency.IsLocal() {
+		return util.DoesPathExist(resolvedDependency.LocalPath)
+	}
+	pkgPath, err := resolvedDependency.GetCachePath()
+	if err != nil {
+		return false, err
+	}
+	exists, err := util.DoesPathExist(pkgPath.Absolute)
+	if err != nil || !exists {
+		return false, err
+	}
+	lock, err := lockCacheEntry(pkgPath.Absolute, true)
+	if err != nil {
+		return false, err
+	}
+	defer lock.Unlock()
+	return ensureCacheEntryComplete(resolvedDependency, pkgPath.Absolute)
+}
+
+// ensureCacheEntryComplete returns if both the package and its metadata are within the cache. Packages cached before
+// metadata was recorded are adopted, writing their metadata, as long as they match the registry's checksum. The
+// caller must hold the entry's exclusive lock.
+func ensureCacheEntryComplete(resolvedDependency ResolvedDependency, pkgPath string) (bool, error) {
+	complete, err := isCacheEntryComplete(pkgPath)
+	if err != nil || complete {
+		return complete, err
+	}
+	exists, err := util.DoesPathExist(pkgPath)
+	if err != nil || !exists {
+		return false, err
+	}
+	if checksum := resolvedDependency.PackageVersion.Checksum; checksum != "" && util.VerifyFileChecksum(pkgPath, checksum) != nil {
+		return false, nil
+	}
+	return true, writeResolvedDependencyMetadata(resolvedDependency, pkgPath)
+}
+
+// writeResolvedDependencyMetadata records where the cached package came from and what it hashed to
+func writeResolvedDependencyMetadata(resolvedDependency ResolvedDependency, pkgPath string) error {
+	digest, err := util.HashFile(pkgPath, "sha256")
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	return writeCacheEntryMetadata(getCacheMetadataPath(pkgPath), CacheEntryMetadata{
+		Registry:    resolvedDependency.Registry.Name,
+		Group:       resolvedDependency.Package.Group,
+		Name:        resolvedDependency.Package.Name,
+		Version:     resolvedDependency.PackageVersion.Number,
+		ArtifactUrl: resolvedDependency.PackageVersion.ArtifactUrl,
+		Checksum:    "sha256:" + digest,
+		CachedAt:    now,
+		LastUsed:    now,
+	})
+}
+
+// MarkCacheEntryUsed records that the resolved dependency's cached package was just used, for pruning
+func MarkCacheEntryUsed(resolvedDependency ResolvedDependency) error {
+	if resolvedDependency.IsLocal() {
+		return nil
+	}
+	pkgPath, err := resolvedDependency.GetCachePath()
+	if err != nil {
+		return err
+	}
+	lock, err := lockCacheEntry(pkgPath.Absolute, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	metadataPath := getCacheMetadataPath(pkgPath.Absolute)
+	metadata, err := readCacheEntryMetadata(metadataPath)
+	if err != nil || metadata == nil {
+		return err
+	}
+	metadata.LastUsed = time.Now().UTC()
+	return writeCacheEntryMetadata(metadataPath, *metadata)
+}
+
+// ListCacheEntries gets every package within the package cache, sorted by group, name and version
+func ListCacheEntries() ([]CacheEntry, error) {
+	cachePath, err := GetPackageCachePath()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return []CacheEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []CacheEntry{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".jar") {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(cachePath, file.Name())
+		metadata, err := readCacheEntryMetadata(getCacheMetadataPath(path))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, CacheEntry{
+			Signature: strings.TrimSuffix(file.Name(), ".jar"),
+			Path:      path,
+			Size:      info.Size(),
+			Metadata:  metadata,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].GetDisplayName() < entries[j].GetDisplayName()
+	})
+	return entries, nil
+}
+
+// GetDisplayName gets the "registry/group:name:version" of the entry, or its signature if that's unknown
+func (entry CacheEntry) GetDisplayName() string {
+	if entry.Metadata == nil {
+		return entry.Signature
+	}
+	return entry.Metadata.Registry + "/" + entry.Metadata.Group + ":" + entry.Metadata.Name + ":" + entry.Metadata.Version
+}
+
+// Verify recomputes the entry's checksum against the one recorded when it was cached
+func (entry CacheEntry) Verify() error {
+	if entry.Metadata == nil || entry.Metadata.Checksum == "" {
+		return errors.New("no checksum was recorded for this package")
+	}
+	return util.VerifyFileChecksum(entry.Path, entry.Metadata.Checksum)
+}
+
+// Remove deletes the entry's package and metadata from the cache, waiting for anyone using it to finish
+func (entry CacheEntry) Remove() error {
+	return removeCacheEntry(entry.Path)
+}
+
+// ClearPackageCache removes every package and its metadata from the package cache. Each is removed under its entry's
+// lock, waiting for anyone downloading or copying it to finish, and the lock files themselves are kept.
+func ClearPackageCache() error {
+	cachePath, err := GetPackageCachePath()
+	if err != nil {
+		return err
+	}
+	files, err := os.ReadDir(cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// metadata may outlive its package, so both lead to the entry
+	pkgPaths := map[string]bool{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if strings.HasSuffix(name, ".jar") {
+			pkgPaths[filepath.Join(cachePath, name)] = true
+		} else if strings.HasSuffix(name, ".json") {
+			pkgPaths[filepath.Join(cachePath, strings.TrimSuffix(name, ".json")+".jar")] = true
+		}
+	}
+	for pkgPath := range pkgPaths {
+		err = removeCacheEntry(pkgPath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeCacheEntry removes the package at the given path and its metadata, either of which may not exist
+func removeCacheEntry(pkgPath string) error {
+	lock, err := lockCacheEntry(pkgPath, true)
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
+
+	for _, path := range []string{getCacheMetadataPath(pkgPath), pkgPath} {
+		err = os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
 	return nil
 }
+
+// CopyCachedPackage copies the resolved dependency's cached package to the destination, waiting for any in progress
+// download or removal of it to finish first.
+func CopyCachedPackage(resolvedDependency ResolvedDependency, destination string) error {
+	if resolvedDependency.IsLocal() {
+		return util.CopyFile(resolvedDependency.LocalPath, destination)
+	}
+	pkgPath, err := resolvedDependency.GetCachePath()
+	if err != nil {
+		return err
+	}
+	lock, err := lockCacheEntry(pkgPath.Absolute, false)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	return util.CopyFile(pkgPath.Absolute, destination)
+}
+
+// lockCacheEntry blocks until a lock is acquired on the cached package at the given path. Writers take an exclusive
+// lock, readers a shared one.
+func lockCacheEntry(pkgPath string, exclusive bool) (*util.FileLock, error) {
+	lockPath := strings.TrimSuffix(pkgPath, ".jar") + ".lock"
+	if exclusive {
+		return util.LockFile(lockPath)
+	}
+	return util.RLockFile(lockPath)
+}
+
+// isCacheEntryComplete returns if both the package and its metadata are within the cache
+func isCacheEntryComplete(pkgPath string) (bool, error) {
+	for _, path := range []string{pkgPath, getCacheMetadataPath(pkgPath)} {
+		exists, err := util.DoesPathExist(path)
+		if err != nil || !exists {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// getCacheMetadataPath gets the path of the metadata file for the cached package at the given path
+func getCacheMetadataPath(pkgPath string) string {
+	return strings.TrimSuffix(pkgPath, ".jar") + ".json"
+}
+
+// readCacheEntryMetadata reads the metadata file at the given path, returning nil if it does not exist
+func readCacheEntryMetadata(path string) (*CacheEntryMetadata, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var metadata CacheEntryMetadata
+	err = json.Unmarshal(content, &metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// writeCacheEntryMetadata writes the metadata file at the given path
+func writeCacheEntryMetadata(path string, metadata CacheEntryMetadata) error {
+	content, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, content, 0644)
+}
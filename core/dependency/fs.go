@@ -12,7 +12,7 @@ import (
 
 // EnsurePackagesDirectory ensures the packages directory exists under the given registry cache.
 func EnsurePackagesDirectory() error {
-	ePath, err := util.GetEspressoDirectoryPath()
+	ePath, err := util.GetEspressoCachePath()
 	if err != nil {
 		return err
 	}
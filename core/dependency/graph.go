@@ -0,0 +1,309 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package dependency
+
+import (
+	"fmt"
+	"sort"
+
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/registry"
+)
+
+// maxGraphIterations bounds how many times version selection is re-run before we give up on it settling
+const maxGraphIterations = 20
+
+// DependencyEdge is a request from a dependent for a particular version of a package
+type DependencyEdge struct {
+	Key       string
+	Requested string
+}
+
+// DependencyNode is a package within the dependency graph at its selected version
+type DependencyNode struct {
+	Group   string
+	Name    string
+	Version string
+	Direct  bool
+	// Overridden is set if the package's version was forced by the project's overrides
+	Overridden bool
+	Resolved   *ResolvedDependency
+	Err        error
+	Children   []DependencyEdge
+}
+
+// DependencyGraph is the resolved graph of a project's direct and transient dependencies. Each package appears once,
+// at the version selected for it: direct dependencies always keep their declared version, otherwise the highest
+// requested version wins.
+type DependencyGraph struct {
+	Roots []DependencyEdge
+	Nodes map[string]*DependencyNode
+}
+
+// GetDependencyKey gets the key identifying a package within the graph (ex: org.slf4j:slf4j-api)
+func GetDependencyKey(group string, name string) string {
+	return group + ":" + name
+}
+
+// Coordinate gets the "group:name:version" of the node's selected version
+func (node *DependencyNode) Coordinate() string {
+	return node.Group + ":" + node.Name + ":" + node.Version
+}
+
+// Scope gets the classpath scope of the node, "processor" for annotation processors and "compile" otherwise
+func (node *DependencyNode) Scope() string {
+	if node.Resolved != nil && node.Resolved.PackageVersion.IsAnnotationProcessor {
+		return "processor"
+	}
+	return "compile"
+}
+
+// IsConflict returns if the given edge requested a different version than the one selected for its package
+func (graph *DependencyGraph) IsConflict(edge DependencyEdge) bool {
+	node, ok := graph.Nodes[edge.Key]
+	return ok && edge.Requested != node.Version && registry.CompareVersionNumbers(edge.Requested, node.Version) != 0
+}
+
+// ResolveDependencyGraph resolves the project's direct dependencies and all of their transient dependencies. Packages
+// that fail to resolve are kept in the graph with their error set, use GetResolvedDependencies to require that
+// everything resolved. Local dependencies have no transient dependencies and are left out of the graph.
+//
+// The project's overrides force a package's version wherever it appears, even over a direct dependency. A direct
+// dependency's exclusions drop packages from beneath it, a package is only left out of the graph if every path to it
+// excludes it.
+func ResolveDependencyGraph(cfg project.ProjectConfig) (*DependencyGraph, error) {
+	return resolveDependencyGraph(cfg, func(group string, name string, number string) (ResolvedDependency, error) {
+		version, err := project.ParseVersion(number)
+		if err != nil {
+			return ResolvedDependency{}, err
+		}
+		return ResolveDependency(project.Dependency{Group: group, Name: name, Version: version}, cfg.Registries)
+	})
+}
+
+// resolveDependencyGraph is ResolveDependencyGraph, resolving each package at a version with the given function
+func resolveDependencyGraph(cfg project.ProjectConfig, resolvePackage func(group string, name string, number string) (ResolvedDependency, error)) (*DependencyGraph, error) {
+	// direct dependencies are pinned to their declared version, overrides to theirs
+	pinned := map[string]string{}
+	direct := map[string]bool{}
+	roots := []DependencyEdge{}
+	rootExclusions := map[string]map[string]bool{}
+	for _, dep := range cfg.Dependencies {
+		if dep.IsLocal() {
+			continue
+		}
+		key := GetDependencyKey(dep.Group, dep.Name)
+		pinned[key] = project.GetVersionAsString(dep.Version)
+		direct[key] = true
+		roots = append(roots, DependencyEdge{Key: key, Requested: pinned[key]})
+		rootExclusions[key] = map[string]bool{}
+		for _, exclusion := range dep.Exclusions {
+			group, name, _, err := ParseCoordinate(exclusion)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' has an invalid exclusion: %w", key, err)
+			}
+			rootExclusions[key][GetDependencyKey(group, name)] = true
+		}
+	}
+	overridden := map[string]bool{}
+	for _, override := range cfg.Overrides {
+		key := GetDependencyKey(override.Group, override.Name)
+		pinned[key] = project.GetVersionAsString(override.Version)
+		overridden[key] = true
+	}
+
+	// cache resolutions across iterations, resolving may walk the registries
+	type resolution struct {
+		resolved ResolvedDependency
+		err      error
+	}
+	cache := map[string]resolution{}
+	resolve := func(group string, name string, number string) (ResolvedDependency, error) {
+		coordinate := group + ":" + name + ":" + number
+		if cached, ok := cache[coordinate]; ok {
+			return cached.resolved, cached.err
+		}
+		resolved, err := resolvePackage(group, name, number)
+		cache[coordinate] = resolution{resolved: resolved, err: err}
+		return resolved, err
+	}
+
+	// walk the graph, re-selecting versions until the selection settles
+	selected := map[string]string{}
+	for key, number := range pinned {
+		selected[key] = number
+	}
+	type visit struct {
+		key        string
+		exclusions map[string]bool
+	}
+	var nodes map[string]*DependencyNode
+	for iteration := 0; ; iteration++ {
+		if iteration >= maxGraphIterations {
+			return nil, fmt.Errorf("dependency version selection did not settle after %d iterations", maxGraphIterations)
+		}
+
+		nodes = map[string]*DependencyNode{}
+		requests := map[string][]string{}
+		queue := []visit{}
+		for _, root := range roots {
+			queue = append(queue, visit{key: root.Key, exclusions: rootExclusions[root.Key]})
+		}
+
+		// a package is expanded with the exclusions shared by every path to it, so it's expanded again whenever a new
+		// path narrows them
+		nodeExclusions := map[string]map[string]bool{}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			key := current.key
+			exclusions := current.exclusions
+			node, visited := nodes[key]
+			if visited {
+				narrowed := map[string]bool{}
+				for exclusion := range nodeExclusions[key] {
+					if exclusions[exclusion] {
+						narrowed[exclusion] = true
+					}
+				}
+				if len(narrowed) == len(nodeExclusions[key]) {
+					continue
+				}
+				exclusions = narrowed
+			} else {
+				// resolve this package at its selected version
+				group, name, _, _ := ParseCoordinate(key)
+				node = &DependencyNode{Group: group, Name: name, Version: selected[key], Direct: direct[key], Overridden: overridden[key]}
+				nodes[key] = node
+				resolved, err := resolve(group, name, selected[key])
+				if err != nil {
+					node.Err = err
+				} else {
+					node.Resolved = &resolved
+					node.Version = resolved.PackageVersion.Number
+				}
+			}
+			nodeExclusions[key] = exclusions
+			if node.Resolved == nil {
+				continue
+			}
+
+			// queue up its transient dependencies that aren't excluded
+			node.Children = []DependencyEdge{}
+			for _, transient := range node.Resolved.PackageVersion.TransientDependencies {
+				childGroup, childName, childNumber, err := ParseCoordinate(transient)
+				if err != nil || childNumber == "" {
+					node.Err = fmt.Errorf("'%s' has an invalid transient dependency '%s'", node.Coordinate(), transient)
+					continue
+				}
+				childKey := GetDependencyKey(childGroup, childName)
+				if exclusions[childKey] {
+					continue
+				}
+				node.Children = append(node.Children, DependencyEdge{Key: childKey, Requested: childNumber})
+				requests[childKey] = append(requests[childKey], childNumber)
+				if _, ok := selected[childKey]; !ok {
+					selected[childKey] = childNumber
+				}
+				queue = append(queue, visit{key: childKey, exclusions: exclusions})
+			}
+		}
+
+		// select the highest requested version of each transient package
+		changed := false
+		for key := range selected {
+			if _, ok := pinned[key]; ok {
+				continue
+			}
+			if _, ok := requests[key]; !ok {
+				delete(selected, key)
+				continue
+			}
+			highest := requests[key][0]
+			for _, number := range requests[key][1:] {
+				if registry.CompareVersionNumbers(number, highest) > 0 {
+					highest = number
+				}
+			}
+			if registry.CompareVersionNumbers(highest, selected[key]) != 0 {
+				selected[key] = highest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	return &DependencyGraph{Roots: roots, Nodes: nodes}, nil
+}
+
+// GetResolvedDependencies gets every package within the graph in breadth first order, direct dependencies first.
+// Returns an error if any package failed to resolve.
+func (graph *DependencyGraph) GetResolvedDependencies() ([]ResolvedDependency, error) {
+	resolved := []ResolvedDependency{}
+	for _, key := range graph.GetKeys() {
+		node := graph.Nodes[key]
+		if node.Err != nil {
+			return nil, node.Err
+		}
+		resolved = append(resolved, *node.Resolved)
+	}
+	return resolved, nil
+}
+
+// GetKeys gets the key of every package within the graph in breadth first order, direct dependencies first
+func (graph *DependencyGraph) GetKeys() []string {
+	keys := []string{}
+	seen := map[string]bool{}
+	queue := append([]DependencyEdge{}, graph.Roots...)
+	for len(queue) > 0 {
+		edge := queue[0]
+		queue = queue[1:]
+		if seen[edge.Key] {
+			continue
+		}
+		seen[edge.Key] = true
+		keys = append(keys, edge.Key)
+		if node, ok := graph.Nodes[edge.Key]; ok {
+			queue = append(queue, node.Children...)
+		}
+	}
+	return keys
+}
+
+// GetPaths gets every path from a direct dependency to the given package, each path being a list of edges starting at
+// the direct dependency and ending at the package. Paths through cycles are not followed.
+func (graph *DependencyGraph) GetPaths(key string) [][]DependencyEdge {
+	paths := [][]DependencyEdge{}
+	var walk func(edge DependencyEdge, path []DependencyEdge, onPath map[string]bool)
+	walk = func(edge DependencyEdge, path []DependencyEdge, onPath map[string]bool) {
+		if onPath[edge.Key] {
+			return
+		}
+		path = append(path, edge)
+		if edge.Key == key {
+			paths = append(paths, append([]DependencyEdge{}, path...))
+			return
+		}
+		node, ok := graph.Nodes[edge.Key]
+		if !ok {
+			return
+		}
+		onPath[edge.Key] = true
+		for _, child := range node.Children {
+			walk(child, path, onPath)
+		}
+		delete(onPath, edge.Key)
+	}
+	for _, root := range graph.Roots {
+		walk(root, nil, map[string]bool{})
+	}
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i]) < len(paths[j])
+	})
+	return paths
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package dependency
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/registry"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// LocalGroup is the group given to local jars that don't declare one
+const LocalGroup = "local"
+
+// ResolveLocalDependency resolves a dependency pointing at a local jar, or a directory of jars, relative to the given
+// project directory. Each jar becomes its own resolved dependency named after its file, with its SHA-256 checksum
+// recorded. The jars are verified against the dependency's recorded checksums, if it has any.
+func ResolveLocalDependency(dep project.Dependency, projectPath string) ([]ResolvedDependency, error) {
+	// find the jars
+	path, jars, err := findLocalDependencyJars(dep, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	err = verifyLocalDependency(dep, info.IsDir(), jars)
+	if err != nil {
+		return nil, err
+	}
+
+	// a single jar may be given a name and version, a directory's jars are named after their files
+	group := dep.Group
+	if group == "" {
+		group = LocalGroup
+	}
+	resolved := []ResolvedDependency{}
+	for _, jar := range jars {
+		name := strings.TrimSuffix(filepath.Base(jar), ".jar")
+		number := "local"
+		if !info.IsDir() {
+			if dep.Name != "" {
+				name = dep.Name
+			}
+			if dep.Version != (project.Version{}) {
+				number = project.GetVersionAsString(dep.Version)
+			}
+		}
+		digest, err := util.HashFile(jar, "sha256")
+		if err != nil {
+			return nil, err
+		}
+
+		pkg := registry.Package{Group: group, Name: name, Registry: project.Registry{Name: LocalGroup}}
+		version := registry.PackageVersionDeclaration{Number: number, Checksum: "sha256:" + digest}
+		pkg.Versions = []registry.PackageVersionDeclaration{version}
+		resolved = append(resolved, ResolvedDependency{
+			Dependency:     dep,
+			Package:        pkg,
+			PackageVersion: version,
+			Registry:       pkg.Registry,
+			LocalPath:      jar,
+		})
+	}
+	return resolved, nil
+}
+
+// RecordLocalChecksums records the SHA-256 checksum of every local jar that doesn't have one recorded yet within the
+// config: the checksum of a single jar, or those of a directory's jars. Returns if any were recorded.
+func RecordLocalChecksums(cfg *project.ProjectConfig) (bool, error) {
+	projectPath, err := project.GetProjectPath()
+	if err != nil {
+		return false, err
+	}
+	recorded := false
+	for i, dep := range cfg.Dependencies {
+		if !dep.IsLocal() || dep.Checksum != "" || len(dep.Checksums) > 0 {
+			continue
+		}
+		path, jars, err := findLocalDependencyJars(dep, projectPath)
+		if err != nil {
+			return false, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		checksums := map[string]string{}
+		for _, jar := range jars {
+			digest, err := util.HashFile(jar, "sha256")
+			if err != nil {
+				return false, err
+			}
+			checksums[filepath.Base(jar)] = "sha256:" + digest
+		}
+		if info.IsDir() {
+			cfg.Dependencies[i].Checksums = checksums
+		} else {
+			cfg.Dependencies[i].Checksum = checksums[filepath.Base(jars[0])]
+		}
+		recorded = true
+	}
+	return recorded, nil
+}
+
+// findLocalDependencyJars gets the absolute path of a local dependency and the jars it refers to
+func findLocalDependencyJars(dep project.Dependency, projectPath string) (string, []string, error) {
+	path := dep.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(projectPath, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("local dependency '%s' is unavailable: %w", dep.Path, err)
+	}
+	if !info.IsDir() {
+		if !strings.HasSuffix(path, ".jar") {
+			return "", nil, fmt.Errorf("local dependency '%s' is not a jar", dep.Path)
+		}
+		return path, []string{path}, nil
+	}
+	jars, err := filepath.Glob(filepath.Join(path, "*.jar"))
+	if err != nil {
+		return "", nil, err
+	}
+	sort.Strings(jars)
+	if len(jars) == 0 {
+		return "", nil, fmt.Errorf("local dependency '%s' does not contain any jars", dep.Path)
+	}
+	return path, jars, nil
+}
+
+// verifyLocalDependency ensures a local dependency's jars are those its checksums were recorded for, unchanged
+func verifyLocalDependency(dep project.Dependency, isDir bool, jars []string) error {
+	const hint = "remove its recorded checksum(s) from espresso.yml and run 'espresso dependency sync' to accept it"
+	if !isDir {
+		if dep.Checksum == "" {
+			return nil
+		}
+		err := util.VerifyFileChecksum(jars[0], dep.Checksum)
+		if err != nil {
+			return fmt.Errorf("local dependency '%s' changed since its checksum was recorded, %s: %w", dep.Path, hint, err)
+		}
+		return nil
+	}
+	if len(dep.Checksums) == 0 {
+		return nil
+	}
+
+	found := map[string]bool{}
+	for _, jar := range jars {
+		fileName := filepath.Base(jar)
+		found[fileName] = true
+		checksum, ok := dep.Checksums[fileName]
+		if !ok {
+			return fmt.Errorf("'%s' was added to local dependency '%s' since its checksums were recorded, %s", fileName, dep.Path, hint)
+		}
+		err := util.VerifyFileChecksum(jar, checksum)
+		if err != nil {
+			return fmt.Errorf("'%s' of local dependency '%s' changed since its checksum was recorded, %s: %w", fileName, dep.Path, hint, err)
+		}
+	}
+	for _, fileName := range getSortedChecksumNames(dep.Checksums) {
+		if !found[fileName] {
+			return fmt.Errorf("'%s' was removed from local dependency '%s' since its checksums were recorded, %s", fileName, dep.Path, hint)
+		}
+	}
+	return nil
+}
+
+// getSortedChecksumNames gets the file names of the recorded checksums in order
+func getSortedChecksumNames(checksums map[string]string) []string {
+	names := []string{}
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveProjectDependencies resolves every package the current project depends upon: its local dependencies followed
+// by its dependency graph with exclusions and overrides applied. In vendor mode, dependencies are instead resolved
+// solely from the project's vendor directory.
+func ResolveProjectDependencies(cfg project.ProjectConfig) ([]ResolvedDependency, error) {
+	if util.IsVendorMode() {
+		resolved, err := ResolveVendoredDependencies(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return resolved, verifyLibFileNames(resolved)
+	}
+	projectPath, err := project.GetProjectPath()
+	if err != nil {
+		return nil, err
+	}
+	resolved := []ResolvedDependency{}
+	for _, dep := range cfg.Dependencies {
+		if !dep.IsLocal() {
+			continue
+		}
+		local, err := ResolveLocalDependency(dep, projectPath)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, local...)
+	}
+
+	// resolve the graph
+	graph, err := ResolveDependencyGraph(cfg)
+	if err != nil {
+		return nil, err
+	}
+	graphResolved, err := graph.GetResolvedDependencies()
+	if err != nil {
+		return nil, err
+	}
+	resolved = append(resolved, graphResolved...)
+	return resolved, verifyLibFileNames(resolved)
+}
+
+// verifyLibFileNames ensures no two dependencies would be copied over one another within the libs directory (ex: two
+// local directories both holding a sdk.jar)
+func verifyLibFileNames(resolved []ResolvedDependency) error {
+	seen := map[string]ResolvedDependency{}
+	for _, rdep := range resolved {
+		name := rdep.GetLibFileName()
+		if previous, ok := seen[name]; ok {
+			return fmt.Errorf("both %s and %s would be distributed as 'libs/%s', rename one of them", getLibSourceName(previous), getLibSourceName(rdep), name)
+		}
+		seen[name] = rdep
+	}
+	return nil
+}
+
+// getLibSourceName describes where a dependency's jar comes from, for errors
+func getLibSourceName(rdep ResolvedDependency) string {
+	if rdep.IsLocal() {
+		return "'" + rdep.LocalPath + "'"
+	}
+	return fmt.Sprintf("'%s:%s:%s'", rdep.Package.Group, rdep.Package.Name, rdep.PackageVersion.Number)
+}
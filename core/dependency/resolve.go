@@ -6,6 +6,7 @@ package dependency
 
 import (
 	"fmt"
+	"strings"
 
 	"kerosenelabs.com/espresso/core/context/project"
 	"kerosenelabs.com/espresso/core/registry"
@@ -19,6 +20,23 @@ type ResolvedDependency struct {
 	PackageVersion   registry.PackageVersionDeclaration
 	Registry         project.Registry
 	PackageSignature string
+	// LocalPath is the jar on disk for dependencies declared by path, which bypass the package cache
+	LocalPath string
+}
+
+// IsLocal returns if the dependency was resolved from a local path rather than a registry
+func (resolvedDependency ResolvedDependency) IsLocal() bool {
+	return resolvedDependency.LocalPath != ""
+}
+
+// GetLibFileName gets the file name of the dependency's jar within an application's libs directory (ex:
+// org.slf4j-slf4j-api-2.0.9.jar), which its manifest's Class-Path refers to
+func (resolvedDependency ResolvedDependency) GetLibFileName() string {
+	parts := []string{resolvedDependency.Package.Name, resolvedDependency.PackageVersion.Number}
+	if resolvedDependency.Package.Group != "" {
+		parts = append([]string{resolvedDependency.Package.Group}, parts...)
+	}
+	return strings.Join(parts, "-") + ".jar"
 }
 
 // GetCachePath returns the absolute filesystem path to where the cached package should be located. Please note,
@@ -26,12 +44,15 @@ type ResolvedDependency struct {
 //
 // You should use PackageCachePath.DoesExist() to ensure this path exists if you're depennding on the package.
 func (resolvedDependency ResolvedDependency) GetCachePath() (util.Path, error) {
+	if resolvedDependency.IsLocal() {
+		return util.Path{Absolute: resolvedDependency.LocalPath}, nil
+	}
 	packageSignature := registry.CalculatePackageSignature(resolvedDependency.Registry, resolvedDependency.Package, resolvedDependency.PackageVersion)
-	espressoPath, err := util.GetEspressoDirectoryPath()
+	cachePath, err := GetPackageCachePath()
 	if err != nil {
 		return util.Path{}, err
 	}
-	pkgPath := espressoPath + "/cachedPackages/" + packageSignature + ".jar"
+	pkgPath := cachePath + "/" + packageSignature + ".jar"
 	return util.Path{Absolute: pkgPath}, nil
 }
 
@@ -44,28 +65,63 @@ func ResolveDependency(dependency project.Dependency, registries []project.Regis
 
 	// iterate over each registry
 	for _, reg := range registries {
-		// get this registry's packages on the filesystem cache
-		pkgs, err := registry.GetRegistryPackages(reg)
+		// find the package within this registry
+		pkg, err := registry.FindPackage(reg, dependency.Group, dependency.Name)
 		if err != nil {
 			return ResolvedDependency{}, err
 		}
-		// iterate over each package
-		for _, pkg := range pkgs {
-			// if we have a match via group and name, match a version
-			if pkg.Group == dependency.Group && pkg.Name == dependency.Name {
-				for _, version := range pkg.Versions {
-					if version.Number == depVersionStr {
-						return ResolvedDependency{
-							Dependency:       dependency,
-							Package:          pkg,
-							PackageVersion:   version,
-							Registry:         reg,
-							PackageSignature: registry.CalculatePackageSignature(reg, pkg, version),
-						}, nil
-					}
-				}
-			}
+		if pkg == nil {
+			continue
+		}
+
+		// if we have a match via group and name, match a version
+		version, found, err := registry.GetPackageVersion(*pkg, depVersionStr)
+		if err != nil {
+			return ResolvedDependency{}, err
+		}
+		if found {
+			return ResolvedDependency{
+				Dependency:       dependency,
+				Package:          *pkg,
+				PackageVersion:   version,
+				Registry:         reg,
+				PackageSignature: registry.CalculatePackageSignature(reg, *pkg, version),
+			}, nil
 		}
 	}
 	return ResolvedDependency{}, fmt.Errorf("'%s:%s:%s' dependency was unable to be resolved within any given registry", dependency.Group, dependency.Name, depVersionStr)
 }
+
+// ParseCoordinate parses a "group:name" or "group:name:version" coordinate. The version is empty if it was omitted.
+func ParseCoordinate(coordinate string) (string, string, string, error) {
+	parts := strings.Split(coordinate, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" || (len(parts) == 3 && parts[2] == "") {
+		return "", "", "", fmt.Errorf("'%s' must be in the group:name or group:name:version format", coordinate)
+	}
+	if len(parts) == 2 {
+		return parts[0], parts[1], "", nil
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// FindLatestVersion finds the newest version of a package across all of the given registries.
+func FindLatestVersion(group string, name string, registries []project.Registry) (string, error) {
+	latest := ""
+	for _, reg := range registries {
+		pkg, err := registry.FindPackage(reg, group, name)
+		if err != nil {
+			return "", err
+		}
+		if pkg == nil {
+			continue
+		}
+		version := registry.GetLatestPackageVersion(*pkg)
+		if version != nil && (latest == "" || registry.CompareVersionNumbers(version.Number, latest) > 0) {
+			latest = version.Number
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("'%s:%s' was not found within any given registry", group, name)
+	}
+	return latest, nil
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package dependency
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/registry"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// VendoredPackage is a package copied into the project's vendor directory
+type VendoredPackage struct {
+	Group                 string `yaml:"group"`
+	Name                  string `yaml:"name"`
+	Version               string `yaml:"version"`
+	Registry              string `yaml:"registry"`
+	File                  string `yaml:"file"`
+	Checksum              string `yaml:"checksum"`
+	IsAnnotationProcessor bool   `yaml:"isAnnotationProcessor,omitempty"`
+	// Dependencies are the package's transient dependencies, so the graph can be resolved without the registries
+	Dependencies []string `yaml:"dependencies,omitempty"`
+}
+
+// VendorManifest is the file format of vendor/vendor.yml, mapping each vendored package to its file and hash
+type VendorManifest struct {
+	Packages []VendoredPackage `yaml:"packages"`
+}
+
+// Coordinate gets the "group:name:version" of the vendored package
+func (pkg VendoredPackage) Coordinate() string {
+	return pkg.Group + ":" + pkg.Name + ":" + pkg.Version
+}
+
+// GetVendorPath gets the path of the project's vendor directory
+func GetVendorPath() (string, error) {
+	projectPath, err := project.GetProjectPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(projectPath, "vendor"), nil
+}
+
+// ReadVendorManifest reads the project's vendor manifest, returning nil if the project has not been vendored
+func ReadVendorManifest() (*VendorManifest, error) {
+	vendorPath, err := GetVendorPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(vendorPath, "vendor.yml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest VendorManifest
+	err = yaml.Unmarshal(content, &manifest)
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// VendorDependencies copies the given resolved dependencies from the package cache into the project's vendor directory
+// and writes its manifest. Packages vendored previously that are no longer given are removed. Local dependencies are
+// already within the project and are skipped.
+func VendorDependencies(resolvedDependencies []ResolvedDependency) (*VendorManifest, error) {
+	vendorPath, err := GetVendorPath()
+	if err != nil {
+		return nil, err
+	}
+	previous, err := ReadVendorManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	// copy each package
+	manifest := VendorManifest{Packages: []VendoredPackage{}}
+	for _, resolved := range resolvedDependencies {
+		if resolved.IsLocal() {
+			continue
+		}
+		file := filepath.Join(resolved.Package.Group, resolved.Package.Name+"-"+resolved.PackageVersion.Number+".jar")
+		destination := filepath.Join(vendorPath, file)
+		err = os.MkdirAll(filepath.Dir(destination), 0755)
+		if err != nil {
+			return nil, err
+		}
+		err = CopyCachedPackage(resolved, destination)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := util.HashFile(destination, "sha256")
+		if err != nil {
+			return nil, err
+		}
+		manifest.Packages = append(manifest.Packages, VendoredPackage{
+			Group:                 resolved.Package.Group,
+			Name:                  resolved.Package.Name,
+			Version:               resolved.PackageVersion.Number,
+			Registry:              resolved.Registry.Name,
+			File:                  filepath.ToSlash(file),
+			Checksum:              "sha256:" + digest,
+			IsAnnotationProcessor: resolved.PackageVersion.IsAnnotationProcessor,
+			Dependencies:          resolved.PackageVersion.TransientDependencies,
+		})
+	}
+	sort.SliceStable(manifest.Packages, func(i, j int) bool {
+		return manifest.Packages[i].Coordinate() < manifest.Packages[j].Coordinate()
+	})
+
+	// remove what's no longer vendored
+	if previous != nil {
+		current := map[string]bool{}
+		for _, pkg := range manifest.Packages {
+			current[pkg.File] = true
+		}
+		for _, pkg := range previous.Packages {
+			if current[pkg.File] {
+				continue
+			}
+			err = os.Remove(filepath.Join(vendorPath, filepath.FromSlash(pkg.File)))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+	}
+
+	// write the manifest
+	content, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	err = util.WriteFileAtomic(filepath.Join(vendorPath, "vendor.yml"), content, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// ResolveVendoredDependencies resolves the project's dependencies solely from its vendor directory, verifying each
+// vendored package against its recorded hash. The dependency graph is resolved against the vendored packages, it must
+// need every one of them and find each package it needs.
+func ResolveVendoredDependencies(cfg project.ProjectConfig) ([]ResolvedDependency, error) {
+	manifest, err := ReadVendorManifest()
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, errors.New("the project has not been vendored, run 'espresso vendor' first")
+	}
+	vendorPath, err := GetVendorPath()
+	if err != nil {
+		return nil, err
+	}
+	projectPath, err := project.GetProjectPath()
+	if err != nil {
+		return nil, err
+	}
+
+	// local dependencies are resolved from their paths
+	resolved := []ResolvedDependency{}
+	for _, dep := range cfg.Dependencies {
+		if !dep.IsLocal() {
+			continue
+		}
+		local, err := ResolveLocalDependency(dep, projectPath)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, local...)
+	}
+
+	// the rest from the graph, which only knows of the vendored packages
+	graph, err := resolveDependencyGraph(cfg, func(group string, name string, number string) (ResolvedDependency, error) {
+		return resolveVendoredPackage(*manifest, vendorPath, group, name, number)
+	})
+	if err != nil {
+		return nil, err
+	}
+	graphResolved, err := graph.GetResolvedDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	// packages the graph no longer needs mean the vendor directory is out of date
+	needed := map[string]bool{}
+	for _, rdep := range graphResolved {
+		needed[rdep.Package.Group+":"+rdep.Package.Name+":"+rdep.PackageVersion.Number] = true
+	}
+	for _, pkg := range manifest.Packages {
+		if !needed[pkg.Coordinate()] {
+			return nil, fmt.Errorf("'%s' is vendored but no longer needed, run 'espresso vendor' to update the vendor directory", pkg.Coordinate())
+		}
+	}
+	return append(resolved, graphResolved...), nil
+}
+
+// resolveVendoredPackage resolves a package at a version from the vendor manifest, verifying its hash
+func resolveVendoredPackage(manifest VendorManifest, vendorPath string, group string, name string, number string) (ResolvedDependency, error) {
+	for _, pkg := range manifest.Packages {
+		if pkg.Group != group || pkg.Name != name || registry.CompareVersionNumbers(pkg.Version, number) != 0 {
+			continue
+		}
+		path := filepath.Join(vendorPath, filepath.FromSlash(pkg.File))
+		err := util.VerifyFileChecksum(path, pkg.Checksum)
+		if err != nil {
+			return ResolvedDependency{}, fmt.Errorf("vendored package '%s' failed verification: %w", pkg.Coordinate(), err)
+		}
+		reg := project.Registry{Name: pkg.Registry}
+		version := registry.PackageVersionDeclaration{
+			Number:                pkg.Version,
+			Checksum:              pkg.Checksum,
+			IsAnnotationProcessor: pkg.IsAnnotationProcessor,
+			TransientDependencies: pkg.Dependencies,
+		}
+		return ResolvedDependency{
+			Dependency:     project.Dependency{Group: pkg.Group, Name: pkg.Name},
+			Package:        registry.Package{Group: pkg.Group, Name: pkg.Name, Versions: []registry.PackageVersionDeclaration{version}, Registry: reg},
+			PackageVersion: version,
+			Registry:       reg,
+			LocalPath:      path,
+		}, nil
+	}
+	return ResolvedDependency{}, fmt.Errorf("'%s:%s:%s' is not vendored, run 'espresso vendor' to update the vendor directory", group, name, number)
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -16,6 +17,21 @@ import (
 	"kerosenelabs.com/espresso/core/util"
 )
 
+const (
+	// RegistryTypeEspresso is a registry distributed as a zip archive of package declarations (the default)
+	RegistryTypeEspresso = "espresso"
+	// RegistryTypeMaven is a remote repository following the Maven 2 layout (ex: Maven Central, Nexus, Artifactory)
+	RegistryTypeMaven = "maven"
+)
+
+// GetRegistryType gets the type of the given registry, defaulting to RegistryTypeEspresso
+func GetRegistryType(reg project.Registry) string {
+	if reg.Type == "" {
+		return RegistryTypeEspresso
+	}
+	return reg.Type
+}
+
 // Package is a high level abstraction on top of the raw filesystem based registry caching. Package represents
 // a package within the registry, but contains all required runtime information in a convenient struct.
 type Package struct {
@@ -33,11 +49,12 @@ type PackageVersionDeclaration struct {
 	ArtifactUrl           string   `yaml:"artifactUrl"`
 	TransientDependencies []string `yaml:"transientDependencies"`
 	IsAnnotationProcessor bool     `yaml:"isAnnotationProcessor"`
+	Checksum              string   `yaml:"checksum,omitempty"`
 }
 
 // PackageDeclaration is the file format of a package declaration
 type PackageDeclaration struct {
-	Name        string
+	Name        string                      `yaml:"-"`
 	Description string                      `yaml:"description"`
 	Versions    []PackageVersionDeclaration `yaml:"versions"`
 }
@@ -69,9 +86,15 @@ func InvalidateRegistryCache(reg project.Registry) error {
 		return err
 	}
 
+	// wait out anyone using the cache
+	lock, err := lockRegistryCache(reg, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	// delete the registry cache
-	os.RemoveAll(cachePath)
-	return nil
+	return os.RemoveAll(cachePath)
 }
 
 // CacheRegistry downloads a zip archive representing an espresso registry and extracts it to the proper directory
@@ -82,6 +105,13 @@ func CacheRegistry(reg project.Registry) error {
 		return err
 	}
 
+	// wait out anyone using the cache
+	lock, err := lockRegistryCache(reg, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	// if the cache exists, error out
 	doesExist, err := util.DoesPathExist(cachePath)
 	if err != nil {
@@ -90,36 +120,100 @@ func CacheRegistry(reg project.Registry) error {
 	if doesExist {
 		return errors.New("cache exists: must be invalidated or not exist")
 	}
+	return populateRegistryCache(reg, cachePath)
+}
 
-	// create the registry's directory
-	err = os.MkdirAll(cachePath, 0755)
+// RefreshRegistryCache atomically replaces the registry's cache with a freshly downloaded one. Other processes reading
+// the registry wait until the new cache is in place, and the old cache is kept if the download fails.
+func RefreshRegistryCache(reg project.Registry) error {
+	// get our cache path
+	cachePath, err := GetRegistryCachePath(reg)
 	if err != nil {
 		return err
 	}
 
-	// download the registry archive
-	err = util.DownloadFile(cachePath+"/archive.zip", reg.Url)
+	// wait out anyone using the cache
+	lock, err := lockRegistryCache(reg, true)
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
+	return populateRegistryCache(reg, cachePath)
+}
 
-	// extract the archive
-	util.Unzip(cachePath+"/archive.zip", cachePath+"/lookup")
-
-	// check if the registry lookup contains a dependencies folder
-	doesDepsExist, err := util.DoesPathExist(cachePath + "/lookup/espresso-registry-main/packages")
+// populateRegistryCache builds the registry's cache within a temporary directory and swaps it into place at the given
+// cache path, replacing whatever was there. The caller must hold the registry's exclusive lock.
+func populateRegistryCache(reg project.Registry, cachePath string) error {
+	// build the cache next to where it'll live so the rename stays on the same filesystem
+	err := os.MkdirAll(filepath.Dir(cachePath), 0755)
+	if err != nil {
+		return err
+	}
+	tmpPath, err := os.MkdirTemp(filepath.Dir(cachePath), "."+reg.Name+".*.tmp")
 	if err != nil {
-		fmt.Printf("An error occurred while reading the registry's lookup directory: %s\n", err)
+		return err
 	}
-	if !doesDepsExist {
-		fmt.Println("An eror occurred: this registry's lookup appears invalid: no packages directory")
+	defer os.RemoveAll(tmpPath)
+
+	// maven registries are cached lazily, file by file, as packages are resolved
+	switch GetRegistryType(reg) {
+	case RegistryTypeEspresso:
+		// download the registry archive
+		err = util.DownloadFile(tmpPath+"/archive.zip", reg.Url)
+		if err != nil {
+			return err
+		}
+
+		// extract the archive
+		err = util.Unzip(tmpPath+"/archive.zip", tmpPath+"/lookup")
+		if err != nil {
+			return err
+		}
+
+		// check if the registry lookup contains a dependencies folder
+		doesDepsExist, err := util.DoesPathExist(tmpPath + "/lookup/espresso-registry-main/packages")
+		if err != nil {
+			return fmt.Errorf("unable to read the registry's lookup directory: %w", err)
+		}
+		if !doesDepsExist {
+			return errors.New("this registry's lookup appears invalid: no packages directory")
+		}
+	case RegistryTypeMaven:
+	default:
+		return fmt.Errorf("registry '%s' has an unknown type '%s'", reg.Name, reg.Type)
 	}
 
-	return nil
+	// move the old cache aside, move the new one in
+	err = os.Chmod(tmpPath, 0755)
+	if err != nil {
+		return err
+	}
+	oldPath := tmpPath + ".old"
+	err = os.Rename(cachePath, oldPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = os.Rename(tmpPath, cachePath)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(oldPath)
 }
 
-// GetRegistryPackageDeclarations parses all package declarations within the cache for a given registry
+// GetRegistryPackageDeclarations parses all package declarations within the cache for a given registry. Maven
+// registries can't be enumerated, so they never return any packages.
 func GetRegistryPackages(reg project.Registry) ([]Package, error) {
+	if GetRegistryType(reg) == RegistryTypeMaven {
+		return []Package{}, nil
+	}
+
+	// wait out anyone recaching the registry
+	lock, err := lockRegistryCache(reg, false)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
 	// get the package group paths
 	pkgGrpPths, err := walkRegistryLookup(reg)
 	if err != nil {
@@ -169,6 +263,48 @@ func GetRegistryPackages(reg project.Registry) ([]Package, error) {
 	return pkgs, nil
 }
 
+// FindPackage finds the package with the given group and name within the given registry. Returns nil if the registry
+// does not contain the package.
+func FindPackage(reg project.Registry, group string, name string) (*Package, error) {
+	switch GetRegistryType(reg) {
+	case RegistryTypeMaven:
+		return getMavenPackage(reg, group, name)
+	case RegistryTypeEspresso:
+		pkgs, err := GetRegistryPackages(reg)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range pkgs {
+			if pkg.Group == group && pkg.Name == name {
+				return &pkg, nil
+			}
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("registry '%s' has an unknown type '%s'", reg.Name, reg.Type)
+	}
+}
+
+// GetPackageVersion gets the given version of a package with all of its information populated. Returns false if the
+// package does not have the version. Missing trailing version components are treated as zero, so a "1.1" version
+// published to a maven registry will match "1.1.0".
+func GetPackageVersion(pkg Package, number string) (PackageVersionDeclaration, bool, error) {
+	for _, version := range pkg.Versions {
+		if version.Number != number && CompareVersionNumbers(version.Number, number) != 0 {
+			continue
+		}
+		if GetRegistryType(pkg.Registry) == RegistryTypeMaven {
+			version, err := getMavenPackageVersion(pkg, version)
+			if err != nil {
+				return PackageVersionDeclaration{}, false, err
+			}
+			return version, true, nil
+		}
+		return version, true, nil
+	}
+	return PackageVersionDeclaration{}, false, nil
+}
+
 // CalculatePackageSignature generates a unique signature of a package and version. This can be used to uniquely
 // reference a local copy of a packages across registries.
 func CalculatePackageSignature(registry project.Registry, pkg Package, version PackageVersionDeclaration) string {
@@ -0,0 +1,264 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package registry
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+)
+
+// LintIssue is a single problem found within a registry tree
+type LintIssue struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Package  string `json:"package,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Message  string `json:"message"`
+}
+
+// LintOptions configures which checks are enforced by LintRegistry
+type LintOptions struct {
+	// RequireChecksums reports versions without a checksum as errors rather than warnings
+	RequireChecksums bool
+}
+
+var versionNumberPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*([-.+][0-9A-Za-z][0-9A-Za-z.+-]*)?$`)
+var checksumPattern = regexp.MustCompile(`^((sha256:)?[0-9a-fA-F]{64}|sha1:[0-9a-fA-F]{40})$`)
+
+// lintedPackage is a package declaration that parsed successfully, along with where it came from
+type lintedPackage struct {
+	coordinate string
+	path       string
+	decl       *PackageDeclaration
+}
+
+// LintRegistry validates the registry tree at the given path (the directory containing "packages"). Returns every
+// issue found, ordered by path.
+func LintRegistry(registryPath string, opts LintOptions) ([]LintIssue, error) {
+	issues := []LintIssue{}
+	packagesPath := filepath.Join(registryPath, "packages")
+
+	// read every package group
+	groups, err := os.ReadDir(packagesPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the registry's packages directory: %w", err)
+	}
+	pkgs := map[string]lintedPackage{}
+	for _, group := range groups {
+		groupPath := filepath.Join(packagesPath, group.Name())
+		if !group.IsDir() {
+			issues = append(issues, LintIssue{Severity: LintSeverityError, Path: groupPath, Message: "unexpected file, packages must be within a group directory"})
+			continue
+		}
+
+		// parse every package declaration within the group
+		files, err := os.ReadDir(groupPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			declPath := filepath.Join(groupPath, file.Name())
+			if file.IsDir() || !strings.HasSuffix(file.Name(), ".yml") {
+				issues = append(issues, LintIssue{Severity: LintSeverityError, Path: declPath, Message: "unexpected entry, package declarations must be .yml files"})
+				continue
+			}
+			content, err := os.ReadFile(declPath)
+			if err != nil {
+				return nil, err
+			}
+			decl, err := UnmarshalPackageDeclaration(string(content))
+			if err != nil {
+				issues = append(issues, LintIssue{Severity: LintSeverityError, Path: declPath, Message: fmt.Sprintf("unable to parse: %s", err)})
+				continue
+			}
+			decl.Name = strings.TrimSuffix(file.Name(), ".yml")
+			coordinate := group.Name() + ":" + decl.Name
+			pkgs[coordinate] = lintedPackage{coordinate: coordinate, path: declPath, decl: decl}
+		}
+	}
+
+	// validate each package on its own
+	for _, pkg := range pkgs {
+		issues = append(issues, lintPackage(pkg, pkgs, opts)...)
+	}
+
+	// look for cycles between package versions
+	issues = append(issues, lintCycles(pkgs)...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Path != issues[j].Path {
+			return issues[i].Path < issues[j].Path
+		}
+		return issues[i].Version < issues[j].Version
+	})
+	return issues, nil
+}
+
+// lintPackage validates the versions of a single package declaration
+func lintPackage(pkg lintedPackage, pkgs map[string]lintedPackage, opts LintOptions) []LintIssue {
+	issues := []LintIssue{}
+	report := func(severity string, version string, format string, args ...any) {
+		issues = append(issues, LintIssue{Severity: severity, Path: pkg.path, Package: pkg.coordinate, Version: version, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(pkg.decl.Versions) == 0 {
+		report(LintSeverityError, "", "package has no versions")
+	}
+
+	for i, version := range pkg.decl.Versions {
+		// versions must be unique (1.0 and 1.0.0 being the same version) and well-formed
+		if !versionNumberPattern.MatchString(version.Number) {
+			report(LintSeverityError, version.Number, "version number '%s' is not well-formed", version.Number)
+		}
+		for _, previous := range pkg.decl.Versions[:i] {
+			if CompareVersionNumbers(previous.Number, version.Number) != 0 {
+				continue
+			}
+			if previous.Number == version.Number {
+				report(LintSeverityError, version.Number, "version is declared more than once")
+			} else {
+				report(LintSeverityError, version.Number, "version is already declared as '%s'", previous.Number)
+			}
+			break
+		}
+
+		// the artifact must be downloadable
+		artifactUrl, err := url.ParseRequestURI(version.ArtifactUrl)
+		if err != nil || (artifactUrl.Scheme != "http" && artifactUrl.Scheme != "https") || artifactUrl.Host == "" {
+			report(LintSeverityError, version.Number, "artifact url '%s' is not a valid http(s) url", version.ArtifactUrl)
+		}
+
+		// checksums are optional unless required
+		if version.Checksum == "" {
+			severity := LintSeverityWarning
+			if opts.RequireChecksums {
+				severity = LintSeverityError
+			}
+			report(severity, version.Number, "version has no checksum")
+		} else if !checksumPattern.MatchString(version.Checksum) {
+			report(LintSeverityError, version.Number, "checksum '%s' is not a valid sha256 or sha1 digest", version.Checksum)
+		}
+
+		// transient dependencies must resolve within this registry
+		for _, dep := range version.TransientDependencies {
+			coordinate, number, err := splitTransientDependency(dep)
+			if err != nil {
+				report(LintSeverityError, version.Number, "%s", err)
+				continue
+			}
+			target, ok := pkgs[coordinate]
+			if !ok {
+				report(LintSeverityError, version.Number, "transient dependency '%s' does not exist within the registry", dep)
+				continue
+			}
+			if findDeclaredVersion(target.decl, number) == nil {
+				report(LintSeverityError, version.Number, "transient dependency '%s' refers to a version that does not exist within the registry", dep)
+			}
+		}
+	}
+	return issues
+}
+
+// lintCycles reports every package version that participates in a transient dependency cycle
+func lintCycles(pkgs map[string]lintedPackage) []LintIssue {
+	issues := []LintIssue{}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+
+	// walk each node depth first, a node we're still visiting is a back edge
+	var visit func(node string, stack []string)
+	visit = func(node string, stack []string) {
+		state[node] = visiting
+		stack = append(stack, node)
+		coordinate, number, _ := splitTransientDependency(node)
+		pkg := pkgs[coordinate]
+		if version := findDeclaredVersion(pkg.decl, number); version != nil {
+			for _, dep := range version.TransientDependencies {
+				depCoordinate, depNumber, err := splitTransientDependency(dep)
+				if err != nil {
+					continue
+				}
+				target, ok := pkgs[depCoordinate]
+				if !ok {
+					continue
+				}
+				declared := findDeclaredVersion(target.decl, depNumber)
+				if declared == nil {
+					continue
+				}
+				next := depCoordinate + ":" + declared.Number
+				switch state[next] {
+				case unvisited:
+					visit(next, stack)
+				case visiting:
+					cycle := []string{next}
+					for i := len(stack) - 1; i >= 0 && stack[i] != next; i-- {
+						cycle = append([]string{stack[i]}, cycle...)
+					}
+					cycle = append([]string{next}, cycle...)
+					issues = append(issues, LintIssue{
+						Severity: LintSeverityError,
+						Path:     pkg.path,
+						Package:  coordinate,
+						Version:  number,
+						Message:  "dependency cycle: " + strings.Join(cycle, " -> "),
+					})
+				}
+			}
+		}
+		state[node] = visited
+	}
+
+	// visit in a stable order so the output is deterministic
+	nodes := []string{}
+	for coordinate, pkg := range pkgs {
+		for _, version := range pkg.decl.Versions {
+			nodes = append(nodes, coordinate+":"+version.Number)
+		}
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		if state[node] == unvisited {
+			visit(node, nil)
+		}
+	}
+	return issues
+}
+
+// splitTransientDependency splits a "group:name:version" transient dependency into its "group:name" and version
+func splitTransientDependency(dep string) (string, string, error) {
+	parts := strings.Split(dep, ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("transient dependency '%s' is not in the group:name:version format", dep)
+	}
+	return parts[0] + ":" + parts[1], parts[2], nil
+}
+
+// findDeclaredVersion finds the version with the given number within a declaration
+func findDeclaredVersion(decl *PackageDeclaration, number string) *PackageVersionDeclaration {
+	if decl == nil {
+		return nil
+	}
+	for i, version := range decl.Versions {
+		if version.Number == number || CompareVersionNumbers(version.Number, number) == 0 {
+			return &decl.Versions[i]
+		}
+	}
+	return nil
+}
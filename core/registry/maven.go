@@ -0,0 +1,355 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package registry
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// mavenMetadata is the file format of a maven-metadata.xml at the artifact level
+type mavenMetadata struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Versioning struct {
+		Latest   string   `xml:"latest"`
+		Release  string   `xml:"release"`
+		Versions []string `xml:"versions>version"`
+	} `xml:"versioning"`
+}
+
+// mavenDependency is a dependency declared within a .pom
+type mavenDependency struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Scope      string `xml:"scope"`
+	Type       string `xml:"type"`
+	Classifier string `xml:"classifier"`
+	Optional   string `xml:"optional"`
+}
+
+// mavenProperties are the free-form <properties> of a .pom
+type mavenProperties map[string]string
+
+// UnmarshalXML collects each child element of <properties> as a key and its text as the value
+func (p *mavenProperties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*p = mavenProperties{}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var value string
+			err = d.DecodeElement(&value, &t)
+			if err != nil {
+				return err
+			}
+			(*p)[t.Name.Local] = strings.TrimSpace(value)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// mavenPom is the subset of the .pom file format we need for resolution
+type mavenPom struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Parent     struct {
+		GroupId    string `xml:"groupId"`
+		ArtifactId string `xml:"artifactId"`
+		Version    string `xml:"version"`
+	} `xml:"parent"`
+	Properties           mavenProperties   `xml:"properties"`
+	DependencyManagement []mavenDependency `xml:"dependencyManagement>dependencies>dependency"`
+	Dependencies         []mavenDependency `xml:"dependencies>dependency"`
+}
+
+// mavenMetadataTTL is how long a cached maven-metadata.xml is trusted before it's fetched again, as unlike versioned
+// artifacts it changes whenever a new version is published
+const mavenMetadataTTL = 1 * time.Hour
+
+// maxMavenParentDepth limits how many parent poms we'll follow
+const maxMavenParentDepth = 10
+
+var mavenPropertyPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// getMavenArtifactPath gets the repository relative path of an artifact's directory (ex: org/slf4j/slf4j-api)
+func getMavenArtifactPath(group string, name string) string {
+	return strings.ReplaceAll(group, ".", "/") + "/" + name
+}
+
+// getMavenRepositoryUrl gets the url of a file relative to the root of the maven registry
+func getMavenRepositoryUrl(reg project.Registry, relativePath string) string {
+	return strings.TrimSuffix(reg.Url, "/") + "/" + relativePath
+}
+
+// fetchMavenFile downloads a file from the maven registry into the registry's cache, returning its content. Versioned
+// files already within the cache are not downloaded again, maven-metadata.xml is fetched again once it's older than
+// mavenMetadataTTL (falling back to the cached copy if that fails). Returns os.ErrNotExist if the registry does not
+// have the file, and util.ErrOffline if it isn't cached while in offline mode.
+func fetchMavenFile(reg project.Registry, relativePath string) ([]byte, error) {
+	cachePath, err := GetRegistryCachePath(reg)
+	if err != nil {
+		return nil, err
+	}
+	filePath := cachePath + "/maven/" + relativePath
+
+	// wait out anyone invalidating the registry, downloads are atomic so concurrent fetches are harmless
+	lock, err := lockRegistryCache(reg, false)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	// download it if we don't have it yet, or if it's metadata that may have changed since
+	info, err := os.Stat(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	exists := err == nil
+	stale := exists && path.Base(relativePath) == "maven-metadata.xml" && time.Since(info.ModTime()) > mavenMetadataTTL
+	if !exists || (stale && !util.IsOfflineMode()) {
+		err = util.DownloadFile(filePath, getMavenRepositoryUrl(reg, relativePath))
+		var statusErr *util.HTTPStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return nil, os.ErrNotExist
+		}
+		if err != nil && !exists {
+			return nil, err
+		}
+	}
+	return os.ReadFile(filePath)
+}
+
+// getMavenPackage looks up the given package within a maven registry via its maven-metadata.xml. The returned
+// package's versions only carry their number and artifact url, use getMavenPackageVersion to fully populate one.
+func getMavenPackage(reg project.Registry, group string, name string) (*Package, error) {
+	content, err := fetchMavenFile(reg, getMavenArtifactPath(group, name)+"/maven-metadata.xml")
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, util.ErrOffline) {
+		// offline, only previously cached packages are available
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata mavenMetadata
+	err = xml.Unmarshal(content, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maven-metadata.xml for '%s:%s': %w", group, name, err)
+	}
+
+	pkg := Package{
+		Group:    group,
+		Name:     name,
+		Registry: reg,
+	}
+	for _, number := range metadata.Versioning.Versions {
+		pkg.Versions = append(pkg.Versions, PackageVersionDeclaration{
+			Number:      number,
+			ArtifactUrl: getMavenRepositoryUrl(reg, getMavenArtifactPath(group, name)+"/"+number+"/"+name+"-"+number+".jar"),
+		})
+	}
+	pkg.Declaration = PackageDeclaration{Name: name, Versions: pkg.Versions}
+	return &pkg, nil
+}
+
+// getMavenPackageVersion populates the transitive dependencies and checksum of a maven package version from its .pom
+// and checksum sidecar files.
+func getMavenPackageVersion(pkg Package, version PackageVersionDeclaration) (PackageVersionDeclaration, error) {
+	pom, properties, managed, err := getEffectiveMavenPom(pkg.Registry, pkg.Group, pkg.Name, version.Number)
+	if err != nil {
+		return PackageVersionDeclaration{}, err
+	}
+
+	// collect our compile and runtime dependencies
+	version.TransientDependencies = []string{}
+	seen := map[string]bool{}
+	for _, dep := range pom.Dependencies {
+		groupId := interpolateMavenProperties(dep.GroupId, properties)
+		artifactId := interpolateMavenProperties(dep.ArtifactId, properties)
+		if seen[groupId+":"+artifactId] {
+			continue
+		}
+		seen[groupId+":"+artifactId] = true
+
+		// the scope and optional flag may be managed just like the version
+		managedDep := managed[groupId+":"+artifactId]
+		scope := interpolateMavenProperties(getMavenManagedValue(dep.Scope, managedDep.Scope), properties)
+		if scope != "" && scope != "compile" && scope != "runtime" {
+			continue
+		}
+		optional := interpolateMavenProperties(getMavenManagedValue(dep.Optional, managedDep.Optional), properties)
+		if optional == "true" || (dep.Type != "" && dep.Type != "jar") || dep.Classifier != "" {
+			continue
+		}
+		depVersion := interpolateMavenProperties(getMavenManagedValue(dep.Version, managedDep.Version), properties)
+		if depVersion == "" {
+			return PackageVersionDeclaration{}, fmt.Errorf("unable to determine the version of '%s:%s' required by '%s:%s:%s'", groupId, artifactId, pkg.Group, pkg.Name, version.Number)
+		}
+		if strings.HasPrefix(depVersion, "[") || strings.HasPrefix(depVersion, "(") {
+			return PackageVersionDeclaration{}, fmt.Errorf("'%s:%s:%s' requires '%s:%s' within the version range '%s', which isn't supported", pkg.Group, pkg.Name, version.Number, groupId, artifactId, depVersion)
+		}
+		version.TransientDependencies = append(version.TransientDependencies, groupId+":"+artifactId+":"+depVersion)
+	}
+
+	// get the strongest checksum the registry offers
+	jarPath := getMavenArtifactPath(pkg.Group, pkg.Name) + "/" + version.Number + "/" + pkg.Name + "-" + version.Number + ".jar"
+	for _, algorithm := range []string{"sha256", "sha1"} {
+		content, err := fetchMavenFile(pkg.Registry, jarPath+"."+algorithm)
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, util.ErrOffline) {
+			continue
+		}
+		if err != nil {
+			return PackageVersionDeclaration{}, err
+		}
+		// sidecars are sometimes in the "<digest>  <filename>" format
+		fields := strings.Fields(string(content))
+		if len(fields) > 0 {
+			version.Checksum = algorithm + ":" + strings.ToLower(fields[0])
+			break
+		}
+	}
+	return version, nil
+}
+
+// getEffectiveMavenPom reads the .pom for the given coordinate along with its parents, returning the pom, the merged
+// properties and the merged dependency management keyed by "group:name".
+func getEffectiveMavenPom(reg project.Registry, group string, name string, number string) (mavenPom, map[string]string, map[string]mavenDependency, error) {
+	return getEffectiveMavenPomAtDepth(reg, group, name, number, 0)
+}
+
+// getEffectiveMavenPomAtDepth is getEffectiveMavenPom, importDepth being how many BOM imports deep the pom is
+func getEffectiveMavenPomAtDepth(reg project.Registry, group string, name string, number string, importDepth int) (mavenPom, map[string]string, map[string]mavenDependency, error) {
+	var chain []mavenPom
+	for depth := 0; group != "" && depth < maxMavenParentDepth; depth++ {
+		content, err := fetchMavenFile(reg, getMavenArtifactPath(group, name)+"/"+number+"/"+name+"-"+number+".pom")
+		if err != nil {
+			return mavenPom{}, nil, nil, fmt.Errorf("unable to read the pom for '%s:%s:%s': %w", group, name, number, err)
+		}
+		var pom mavenPom
+		err = xml.Unmarshal(content, &pom)
+		if err != nil {
+			return mavenPom{}, nil, nil, fmt.Errorf("invalid pom for '%s:%s:%s': %w", group, name, number, err)
+		}
+		chain = append(chain, pom)
+		group, name, number = pom.Parent.GroupId, pom.Parent.ArtifactId, pom.Parent.Version
+	}
+
+	// merge from the top-most parent down so children win
+	properties := map[string]string{}
+	managed := map[string]mavenDependency{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for k, v := range chain[i].Properties {
+			properties[k] = v
+		}
+		for _, dep := range chain[i].DependencyManagement {
+			if !isMavenBomImport(dep) {
+				managed[dep.GroupId+":"+dep.ArtifactId] = dep
+			}
+		}
+	}
+
+	// expose the built-in project properties
+	pom := chain[0]
+	pomGroup := pom.GroupId
+	if pomGroup == "" {
+		pomGroup = pom.Parent.GroupId
+	}
+	pomVersion := pom.Version
+	if pomVersion == "" {
+		pomVersion = pom.Parent.Version
+	}
+	for _, prefix := range []string{"project.", "pom.", ""} {
+		properties[prefix+"groupId"] = pomGroup
+		properties[prefix+"artifactId"] = pom.ArtifactId
+		properties[prefix+"version"] = pomVersion
+	}
+	properties["project.parent.groupId"] = pom.Parent.GroupId
+	properties["project.parent.version"] = pom.Parent.Version
+
+	// managed coordinates may be declared with properties too
+	resolvedManaged := map[string]mavenDependency{}
+	for coordinate, dep := range managed {
+		resolvedManaged[interpolateMavenProperties(coordinate, properties)] = dep
+	}
+
+	// imported BOMs only fill in what isn't declared directly, the nearest import winning
+	for _, imported := range chain {
+		for _, dep := range imported.DependencyManagement {
+			if !isMavenBomImport(dep) {
+				continue
+			}
+			bomGroup := interpolateMavenProperties(dep.GroupId, properties)
+			bomName := interpolateMavenProperties(dep.ArtifactId, properties)
+			bomVersion := interpolateMavenProperties(dep.Version, properties)
+			if importDepth >= maxMavenParentDepth {
+				return mavenPom{}, nil, nil, fmt.Errorf("too many nested BOM imports at '%s:%s:%s'", bomGroup, bomName, bomVersion)
+			}
+			_, bomProperties, bomManaged, err := getEffectiveMavenPomAtDepth(reg, bomGroup, bomName, bomVersion, importDepth+1)
+			if err != nil {
+				return mavenPom{}, nil, nil, fmt.Errorf("unable to import the BOM '%s:%s:%s': %w", bomGroup, bomName, bomVersion, err)
+			}
+			for coordinate, managedDep := range bomManaged {
+				if _, ok := resolvedManaged[coordinate]; !ok {
+					// the BOM's entries refer to its own properties
+					managedDep.Version = interpolateMavenProperties(managedDep.Version, bomProperties)
+					managedDep.Scope = interpolateMavenProperties(managedDep.Scope, bomProperties)
+					managedDep.Optional = interpolateMavenProperties(managedDep.Optional, bomProperties)
+					resolvedManaged[coordinate] = managedDep
+				}
+			}
+		}
+	}
+
+	// inherit dependencies declared by the parents
+	for _, parent := range chain[1:] {
+		pom.Dependencies = append(pom.Dependencies, parent.Dependencies...)
+	}
+	return pom, properties, resolvedManaged, nil
+}
+
+// isMavenBomImport returns if the managed dependency imports the dependency management of a BOM
+func isMavenBomImport(dep mavenDependency) bool {
+	return strings.TrimSpace(dep.Scope) == "import" && strings.TrimSpace(dep.Type) == "pom"
+}
+
+// getMavenManagedValue gets the value declared on the dependency, or the managed value if it declares none
+func getMavenManagedValue(declared string, managed string) string {
+	if strings.TrimSpace(declared) == "" {
+		return managed
+	}
+	return declared
+}
+
+// interpolateMavenProperties replaces ${...} placeholders within the given value with their property values
+func interpolateMavenProperties(value string, properties map[string]string) string {
+	// properties may reference other properties, bound the passes to avoid cycles
+	for i := 0; i < 10 && strings.Contains(value, "${"); i++ {
+		value = mavenPropertyPattern.ReplaceAllStringFunc(value, func(match string) string {
+			resolved, ok := properties[match[2:len(match)-1]]
+			if !ok {
+				return match
+			}
+			return resolved
+		})
+	}
+	return strings.TrimSpace(value)
+}
@@ -14,14 +14,27 @@ import (
 
 // GetCachePath gets the full cache path from the registry (ex: /home/vscode/.espresso/registries/espresso-registry)
 func GetRegistryCachePath(reg project.Registry) (string, error) {
-	// get our home dir
-	espressoPath, err := util.GetEspressoDirectoryPath()
+	// get our cache dir
+	espressoPath, err := util.GetEspressoCachePath()
 	if err != nil {
 		return "", err
 	}
 	return espressoPath + "/registries/" + reg.Name, nil
 }
 
+// lockRegistryCache blocks until a lock is acquired on the given registry's cache. Mutating the cache requires an
+// exclusive lock, reading it a shared one, so readers wait out a recache rather than reading a partial cache.
+func lockRegistryCache(reg project.Registry, exclusive bool) (*util.FileLock, error) {
+	cachePath, err := GetRegistryCachePath(reg)
+	if err != nil {
+		return nil, err
+	}
+	if exclusive {
+		return util.LockFile(cachePath + ".lock")
+	}
+	return util.RLockFile(cachePath + ".lock")
+}
+
 // GetRegistryCachePackagesLookupPath gets the full cache path of the registry package lookup
 // (ex: /home/vscode/.espresso/registries/espresso-registry/lookup/espresso-registry-main/packages)
 func GetRegistryCachePackagesLookupPath(reg project.Registry) (string, error) {
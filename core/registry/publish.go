@@ -0,0 +1,73 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package registry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// GetPackageDeclarationPath gets the path of a package declaration within a local registry checkout
+// (ex: espresso-registry/packages/org.projectlombok/lombok.yml)
+func GetPackageDeclarationPath(registryPath string, group string, name string) string {
+	return filepath.Join(registryPath, "packages", group, name+".yml")
+}
+
+// PublishPackageVersion writes the given version into the package's declaration within a local registry checkout,
+// creating the declaration if it does not exist. An existing version with the same number is replaced, otherwise the
+// version is inserted in sorted order. The description is only changed if one is given. Returns the path of the
+// written declaration.
+func PublishPackageVersion(registryPath string, group string, name string, description string, version PackageVersionDeclaration) (string, error) {
+	declPath := GetPackageDeclarationPath(registryPath, group, name)
+
+	// read the existing declaration, if any
+	decl := &PackageDeclaration{Versions: []PackageVersionDeclaration{}}
+	exists, err := util.DoesPathExist(declPath)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		content, err := os.ReadFile(declPath)
+		if err != nil {
+			return "", err
+		}
+		decl, err = UnmarshalPackageDeclaration(string(content))
+		if err != nil {
+			return "", fmt.Errorf("unable to parse '%s': %w", declPath, err)
+		}
+	}
+	decl.Name = name
+	if description != "" {
+		decl.Description = description
+	}
+
+	// replace any equivalent version (ex: "1.0" for "1.0.0"), resolution wouldn't tell them apart
+	versions := []PackageVersionDeclaration{version}
+	for _, existing := range decl.Versions {
+		if CompareVersionNumbers(existing.Number, version.Number) != 0 {
+			versions = append(versions, existing)
+		}
+	}
+	decl.Versions = versions
+	SortPackageVersions(decl.Versions)
+
+	// write it back
+	content, err := MarshalPackageDeclaration(decl)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(filepath.Dir(declPath), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(declPath, []byte(content), 0644)
+	if err != nil {
+		return "", err
+	}
+	return declPath, nil
+}
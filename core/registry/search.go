@@ -0,0 +1,137 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package registry
+
+import (
+	"sort"
+	"strings"
+)
+
+// SearchResult is a package matched by SearchPackages along with how well it matched
+type SearchResult struct {
+	Package Package
+	Score   int
+}
+
+// SearchFilter narrows down the packages considered by SearchPackages. Empty fields match everything.
+type SearchFilter struct {
+	Group     string
+	Registry  string
+	Processor bool
+}
+
+// SearchPackages ranks the given packages against the search term, best match first. Every word of the term must
+// fuzzily match the package's name, group or description. An empty term or "*" matches every package.
+func SearchPackages(pkgs []Package, term string, filter SearchFilter) []SearchResult {
+	words := strings.Fields(strings.ToLower(term))
+	if len(words) == 1 && words[0] == "*" {
+		words = nil
+	}
+
+	results := []SearchResult{}
+	for _, pkg := range pkgs {
+		if !matchesSearchFilter(pkg, filter) {
+			continue
+		}
+
+		// score each word, weighting the name over the group over the description
+		score := 0
+		matched := true
+		for _, word := range words {
+			wordScore := 3*scoreFuzzyMatch(pkg.Name, word, true) + 2*scoreFuzzyMatch(pkg.Group, word, true) + scoreFuzzyMatch(pkg.Description, word, false)
+			if wordScore == 0 {
+				matched = false
+				break
+			}
+			score += wordScore
+		}
+		if matched {
+			results = append(results, SearchResult{Package: pkg, Score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Package.Group != results[j].Package.Group {
+			return results[i].Package.Group < results[j].Package.Group
+		}
+		return results[i].Package.Name < results[j].Package.Name
+	})
+	return results
+}
+
+// GetLatestPackageVersion gets the newest version of the given package, or nil if it has no versions
+func GetLatestPackageVersion(pkg Package) *PackageVersionDeclaration {
+	var latest *PackageVersionDeclaration
+	for i, version := range pkg.Versions {
+		if latest == nil || CompareVersionNumbers(version.Number, latest.Number) > 0 {
+			latest = &pkg.Versions[i]
+		}
+	}
+	return latest
+}
+
+// IsAnnotationProcessor returns if any version of the given package is an annotation processor
+func IsAnnotationProcessor(pkg Package) bool {
+	for _, version := range pkg.Versions {
+		if version.IsAnnotationProcessor {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSearchFilter returns if the package passes the given filter
+func matchesSearchFilter(pkg Package, filter SearchFilter) bool {
+	if filter.Group != "" && !strings.EqualFold(pkg.Group, filter.Group) {
+		return false
+	}
+	if filter.Registry != "" && pkg.Registry.Name != filter.Registry {
+		return false
+	}
+	if filter.Processor && !IsAnnotationProcessor(pkg) {
+		return false
+	}
+	return true
+}
+
+// scoreFuzzyMatch scores how well the lowercase word matches the value, zero being no match at all. Exact matches
+// beat prefixes, which beat substrings, which beat in-order subsequences of characters (only considered if fuzzy).
+func scoreFuzzyMatch(value string, word string, fuzzy bool) int {
+	value = strings.ToLower(value)
+	switch {
+	case value == "" || word == "":
+		return 0
+	case value == word:
+		return 100
+	case strings.HasPrefix(value, word):
+		return 75
+	case strings.Contains(value, word):
+		return 50
+	case !fuzzy:
+		return 0
+	}
+
+	// match the characters in order, penalizing the gaps between them
+	gaps := 0
+	position := 0
+	for _, r := range word {
+		index := strings.IndexRune(value[position:], r)
+		if index < 0 {
+			return 0
+		}
+		if position > 0 {
+			gaps += index
+		}
+		position += index + len(string(r))
+	}
+	score := 25 - gaps
+	if score < 1 {
+		score = 1
+	}
+	return score
+}
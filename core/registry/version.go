@@ -0,0 +1,87 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package registry
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// splitVersionNumber splits a version number into its leading numeric components and its qualifier
+// (ex: "1.2.3-beta1" is [1, 2, 3] and "-beta1")
+func splitVersionNumber(number string) ([]int64, string) {
+	end := 0
+	for end < len(number) && (number[end] == '.' || (number[end] >= '0' && number[end] <= '9')) {
+		end++
+	}
+	numeric := strings.Trim(number[:end], ".")
+	qualifier := number[len(strings.TrimSuffix(number[:end], ".")):]
+
+	var components []int64
+	if numeric != "" {
+		for _, part := range strings.Split(numeric, ".") {
+			value, _ := strconv.ParseInt(part, 10, 64)
+			components = append(components, value)
+		}
+	}
+	return components, qualifier
+}
+
+// CompareVersionNumbers compares two version numbers, returning -1 if a is older than b, 1 if a is newer than b and 0
+// if they are equivalent. Missing components are treated as zero and a version with a qualifier (ex: "-rc1") is
+// older than the same version without one.
+func CompareVersionNumbers(a string, b string) int {
+	aComponents, aQualifier := splitVersionNumber(a)
+	bComponents, bQualifier := splitVersionNumber(b)
+
+	// compare the numeric components
+	for i := 0; i < len(aComponents) || i < len(bComponents); i++ {
+		var aValue, bValue int64
+		if i < len(aComponents) {
+			aValue = aComponents[i]
+		}
+		if i < len(bComponents) {
+			bValue = bComponents[i]
+		}
+		if aValue < bValue {
+			return -1
+		}
+		if aValue > bValue {
+			return 1
+		}
+	}
+
+	// compare the qualifiers, a release beats a pre-release
+	switch {
+	case aQualifier == bQualifier:
+		return 0
+	case aQualifier == "":
+		return 1
+	case bQualifier == "":
+		return -1
+	case aQualifier < bQualifier:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// SortPackageVersions sorts the given versions from oldest to newest
+func SortPackageVersions(versions []PackageVersionDeclaration) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return CompareVersionNumbers(versions[i].Number, versions[j].Number) < 0
+	})
+}
+
+// GetVersionNumberComponents gets the major, minor and patch components of a version number, missing components being
+// zero
+func GetVersionNumberComponents(number string) (int64, int64, int64) {
+	components, _ := splitVersionNumber(number)
+	for len(components) < 3 {
+		components = append(components, 0)
+	}
+	return components[0], components[1], components[2]
+}
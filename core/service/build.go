@@ -25,6 +25,10 @@ func BuildProject() {
 		util.ErrorQuit("An error occurred while getting the project context: %s", err)
 	}
 
+	if projectContext.Config.Type != "" && projectContext.Config.Type != project.ProjectTypeApplication && !projectContext.Config.IsLibrary() {
+		util.ErrorQuit("Unknown project type '%s', it must be '%s' or '%s'", projectContext.Config.Type, project.ProjectTypeApplication, project.ProjectTypeLibrary)
+	}
+
 	color.Cyan("- Beginning build of '%s'", projectContext.Config.Name)
 	color.Cyan("-- Note: please ensure you are compliant with all dependency licenses")
 
@@ -34,6 +38,53 @@ func BuildProject() {
 		util.ErrorQuit(fmt.Sprintf("An error occurred while discovering source files: %s\n", err))
 	}
 
+	// modules are compiled as a whole
+	if declaration := source.FindModuleDeclaration(files); declaration != nil {
+		color.Cyan("-- Compiling module '%s'", declaration.Name)
+		err = toolchain.CompileModule(projectContext.Config, files, declaration)
+		if err != nil {
+			util.ErrorQuit("An error occurred while compiling the module: %s\n", err)
+		}
+		color.Black("--- Compiled %d source file(s)", len(files))
+	} else {
+		compileSourceFiles(projectContext.Config, files)
+	}
+
+	// copy the resources next to the classes
+	color.Cyan("-- Copying resources")
+	resources, err := toolchain.CopyResources(projectContext.Config)
+	if err != nil {
+		util.ErrorQuit("An error occurred while copying resources: %s\n", err)
+	}
+	for _, resource := range resources {
+		color.Black("--- Copied: " + resource.RelativePath)
+	}
+
+	// package the project
+	color.Cyan("-- Packaging distributable")
+	err = toolchain.PackageClasses(projectContext.Config)
+	if err != nil {
+		util.ErrorQuit(fmt.Sprintf("An error occurred while packaging the classes: %s\n", err))
+	}
+	color.Blue("-- Finished packaging distributable")
+
+	// libraries are distributed with their sources and javadoc, applications with their dependencies
+	if projectContext.Config.IsLibrary() {
+		packageLibrary(projectContext.Config, files)
+	} else {
+		copyDependencyPackages(projectContext.Config)
+	}
+	color.Green("- Done!")
+}
+
+// compileSourceFiles compiles each source file of a project that isn't modular
+func compileSourceFiles(cfg project.ProjectConfig, files []source.SourceFile) {
+	// resolve the classpath once, every source file shares it
+	cpVal, err := toolchain.GetClassPath(cfg)
+	if err != nil {
+		util.ErrorQuit("An error occurred while resolving the classpath: %s\n", err)
+	}
+
 	// run the compiler on each source file
 	color.Cyan("-- Compiling")
 	var wg sync.WaitGroup
@@ -41,7 +92,7 @@ func BuildProject() {
 		wg.Add(1)
 		go func(f *source.SourceFile) {
 			defer wg.Done()
-			err := toolchain.CompileSourceFile(projectContext.Config, value)
+			err := toolchain.CompileSourceFile(cfg, cpVal, value)
 			if err != nil {
 				util.ErrorQuit("An error occurred while compiling a source file: %s\n", err)
 			}
@@ -49,46 +100,62 @@ func BuildProject() {
 		}(&value)
 	}
 	wg.Wait()
+}
 
-	// package the project
-	color.Cyan("-- Packaging distributable")
-	err = toolchain.PackageClasses(projectContext.Config)
+// packageLibrary creates the sources and javadoc jars of a library project
+func packageLibrary(cfg project.ProjectConfig, files []source.SourceFile) {
+	color.Cyan("-- Packaging sources")
+	jarPath, err := toolchain.PackageSources(cfg)
 	if err != nil {
-		util.ErrorQuit(fmt.Sprintf("An error occurred while packaging the classes: %s\n", err))
+		util.ErrorQuit("An error occurred while packaging the sources: %s\n", err)
 	}
-	color.Blue("-- Finished packaging distributable")
+	color.Black("--- Packaged: " + jarPath)
+
+	color.Cyan("-- Generating javadoc")
+	jarPath, err = toolchain.PackageJavadoc(cfg, files)
+	if err != nil {
+		util.ErrorQuit("An error occurred while generating the javadoc: %s\n", err)
+	}
+	color.Black("--- Packaged: " + jarPath)
+}
 
+// copyDependencyPackages copies every dependency package of an application into dist/libs
+func copyDependencyPackages(cfg project.ProjectConfig) {
 	// iterate over each dependency, resolve it and copy it
-	distPath, err := toolchain.GetDistPath(projectContext.Config)
+	distPath, err := toolchain.GetDistPath(cfg)
 	if err != nil {
 		util.ErrorQuit(fmt.Sprintf("Unable to get dist path: %s", err))
 	}
+	// start from an empty directory so packages of dependencies since removed aren't left behind
+	err = os.RemoveAll(*distPath + "/libs")
+	if err != nil {
+		util.ErrorQuit("Unable to clear the dist libs: %s", err)
+	}
 	os.MkdirAll(*distPath+"/libs", 0755)
 	var depCopyWg sync.WaitGroup
 	color.Cyan("-- Copying dependency packages to distributable")
-	for _, dep := range projectContext.Config.Dependencies {
+	resolvedDependencies, err := dependency.ResolveProjectDependencies(cfg)
+	if err != nil {
+		util.ErrorQuit(fmt.Sprintf("Unable to resolve dependency: %s", err))
+	}
+	for _, resolved := range resolvedDependencies {
 		depCopyWg.Add(1)
 		go func() {
 			defer depCopyWg.Done()
-			resolved, err := dependency.ResolveDependency(dep, projectContext.Config.Registries)
-			if err != nil {
-				util.ErrorQuit(fmt.Sprintf("Unable to resolve dependency: %s", err))
-			}
 
-			// get the cache path for this package
-			cachePath, err := resolved.GetCachePath()
+			// copy the file
+			err := dependency.CopyCachedPackage(resolved, *distPath+"/libs/"+resolved.GetLibFileName())
 			if err != nil {
-				util.ErrorQuit("Unable to find cache path for dependency: %s", err)
+				util.ErrorQuit(fmt.Sprintf("Unable to copy file: %s", err))
 			}
 
-			// copy the file
-			err = util.CopyFile(cachePath.Absolute, fmt.Sprintf(*distPath+"/libs/%s.jar", dep.Name))
+			// keep the package from being pruned
+			err = dependency.MarkCacheEntryUsed(resolved)
 			if err != nil {
-				util.ErrorQuit(fmt.Sprintf("Unable to copy file: %s", err))
+				util.ErrorQuit("Unable to update the package cache metadata: %s", err)
 			}
-			color.Black("--- Copied '%s:%s' to distributable", dep.Group, dep.Name)
+			color.Black("--- Copied '%s:%s' to distributable", resolved.Package.Group, resolved.Package.Name)
 		}()
 	}
 	depCopyWg.Wait()
-	color.Green("- Done!")
 }
@@ -0,0 +1,228 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/dependency"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// cacheEntryOutput is the JSON output format of a single entry within ListCache
+type cacheEntryOutput struct {
+	Signature string                         `json:"signature"`
+	Path      string                         `json:"path"`
+	Size      int64                          `json:"size"`
+	Metadata  *dependency.CacheEntryMetadata `json:"metadata"`
+}
+
+// ListCache is a service function that prints every package within the package cache
+func ListCache(output string) {
+	useProjectCacheSettings()
+	if output != "table" && output != "json" {
+		util.ErrorQuit("Unknown output format '%s'", output)
+	}
+	entries, err := dependency.ListCacheEntries()
+	if err != nil {
+		util.ErrorQuit("An error occurred while reading the package cache: %s", err)
+	}
+
+	if output == "json" {
+		out := []cacheEntryOutput{}
+		for _, entry := range entries {
+			out = append(out, cacheEntryOutput{Signature: entry.Signature, Path: entry.Path, Size: entry.Size, Metadata: entry.Metadata})
+		}
+		printJson(out)
+		return
+	}
+
+	var total int64
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Registry", "Group", "Package", "Version", "Size", "Last Used"})
+	for _, entry := range entries {
+		total += entry.Size
+		if entry.Metadata == nil {
+			table.Append([]string{"?", "?", entry.Signature, "?", formatSize(entry.Size), "?"})
+			continue
+		}
+		table.Append([]string{
+			entry.Metadata.Registry,
+			entry.Metadata.Group,
+			entry.Metadata.Name,
+			entry.Metadata.Version,
+			formatSize(entry.Size),
+			entry.Metadata.LastUsed.Local().Format(time.DateTime),
+		})
+	}
+	table.Render()
+	cachePath, err := dependency.GetPackageCachePath()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the package cache path: %s", err)
+	}
+	color.Cyan("%d package(s), %s in '%s'", len(entries), formatSize(total), cachePath)
+}
+
+// VerifyCache is a service function that recomputes the checksum of every cached package against the one recorded
+// when it was cached. Exits non-zero if any package fails verification.
+func VerifyCache(remove bool) {
+	useProjectCacheSettings()
+	entries, err := dependency.ListCacheEntries()
+	if err != nil {
+		util.ErrorQuit("An error occurred while reading the package cache: %s", err)
+	}
+
+	failed := 0
+	for _, entry := range entries {
+		err := entry.Verify()
+		if err == nil {
+			color.Green("[%s] Verified", entry.GetDisplayName())
+			continue
+		}
+		failed++
+		color.Red("[%s] Failed verification: %s", entry.GetDisplayName(), err)
+		if remove {
+			err = entry.Remove()
+			if err != nil {
+				util.ErrorQuit("[%s] An error occurred while removing the package: %s", entry.GetDisplayName(), err)
+			}
+			color.Yellow("[%s] Removed", entry.GetDisplayName())
+		}
+	}
+
+	if failed > 0 {
+		util.ErrorQuit("%d of %d package(s) failed verification", failed, len(entries))
+	}
+	color.Cyan("%d package(s) verified", len(entries))
+}
+
+// PruneCache is a service function that removes cached packages that are either not referenced by any of the given
+// projects (if any are given) or have not been used within the given amount of days (if greater than zero).
+func PruneCache(projectPaths []string, days int, dryRun bool) {
+	useProjectCacheSettings()
+	if len(projectPaths) == 0 && days <= 0 {
+		util.ErrorQuit("At least one project or a number of days must be given to prune by")
+	}
+
+	// collect every package the given projects reference, a package we can't resolve may still be needed
+	referenced := map[string]bool{}
+	for _, projectPath := range projectPaths {
+		signatures, err := getReferencedSignatures(projectPath)
+		if err != nil {
+			util.ErrorQuit("Nothing was pruned, %s", err)
+		}
+		for signature := range signatures {
+			referenced[signature] = true
+		}
+	}
+
+	// find what to prune
+	entries, err := dependency.ListCacheEntries()
+	if err != nil {
+		util.ErrorQuit("An error occurred while reading the package cache: %s", err)
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+	var reclaimed int64
+	pruned := 0
+	for _, entry := range entries {
+		reason := ""
+		switch {
+		case len(projectPaths) > 0 && !referenced[entry.Signature]:
+			reason = "not referenced by any given project"
+		case days > 0 && (entry.Metadata == nil || entry.Metadata.LastUsed.Before(cutoff)):
+			reason = fmt.Sprintf("not used within %d day(s)", days)
+		default:
+			continue
+		}
+
+		if !dryRun {
+			err = entry.Remove()
+			if err != nil {
+				util.ErrorQuit("[%s] An error occurred while removing the package: %s", entry.GetDisplayName(), err)
+			}
+		}
+		pruned++
+		reclaimed += entry.Size
+		color.Yellow("[%s] Pruned: %s", entry.GetDisplayName(), reason)
+	}
+
+	if dryRun {
+		color.Cyan("Would prune %d package(s), reclaiming %s", pruned, formatSize(reclaimed))
+	} else {
+		color.Cyan("Pruned %d package(s), reclaimed %s", pruned, formatSize(reclaimed))
+	}
+}
+
+// ClearCache is a service function that removes every package from the package cache
+func ClearCache() {
+	useProjectCacheSettings()
+	err := dependency.ClearPackageCache()
+	if err != nil {
+		util.ErrorQuit("An error occurred while clearing the package cache: %s", err)
+	}
+	color.Green("Cleared")
+}
+
+// useProjectCacheSettings loads the current project, if there is one, so a project-local cache is respected
+func useProjectCacheSettings() {
+	configPath, err := project.GetConfigPath()
+	if err != nil {
+		return
+	}
+	if exists, _ := util.DoesPathExist(configPath); exists {
+		project.GetProjectContext()
+	}
+}
+
+// getReferencedSignatures gets the package signature of every dependency within the graph of the project at the given
+// path (either an espresso.yml or the directory containing one). Returns an error unless the whole graph resolves.
+func getReferencedSignatures(projectPath string) (map[string]bool, error) {
+	info, err := os.Stat(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the project at '%s': %w", projectPath, err)
+	}
+	if info.IsDir() {
+		projectPath = filepath.Join(projectPath, "espresso.yml")
+	}
+	cfg, err := project.ReadConfig(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the project config at '%s': %w", projectPath, err)
+	}
+
+	// resolve the project's graph
+	graph, err := dependency.ResolveDependencyGraph(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve the dependencies of '%s': %w", projectPath, err)
+	}
+	resolvedDependencies, err := graph.GetResolvedDependencies()
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve the dependencies of '%s': %w", projectPath, err)
+	}
+	signatures := map[string]bool{}
+	for _, rdep := range resolvedDependencies {
+		signatures[rdep.PackageSignature] = true
+	}
+	return signatures, nil
+}
+
+// formatSize formats a byte count for humans (ex: 1.5 MiB)
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/dependency"
+	"kerosenelabs.com/espresso/core/registry"
+)
+
+// pruneProjectConfig depends on a package that resolves and one whose pom the registry doesn't have
+const pruneProjectConfig = `name: prune
+version:
+    major: 0
+    minor: 1
+    patch: 0
+dependencies:
+    - group: org.example
+      name: ok
+      version:
+        major: 1
+        minor: 0
+        patch: 0
+    - group: org.example
+      name: broken
+      version:
+        major: 1
+        minor: 0
+        patch: 0
+registries:
+    - name: test
+      url: %s
+      type: maven
+`
+
+// TestPruneCacheKeepsPackagesOfUnresolvedNodes ensures pruning by project stops rather than removing the packages of
+// dependencies that failed to resolve
+func TestPruneCacheKeepsPackagesOfUnresolvedNodes(t *testing.T) {
+	// PruneCache exits on errors, so it's run within a child process
+	if projectPath := os.Getenv("ESPRESSO_TEST_PRUNE_PROJECT"); projectPath != "" {
+		PruneCache([]string{projectPath}, 0, false)
+		return
+	}
+
+	files := map[string]string{
+		"/org/example/ok/maven-metadata.xml":     `<metadata><versioning><versions><version>1.0.0</version></versions></versioning></metadata>`,
+		"/org/example/ok/1.0.0/ok-1.0.0.pom":     `<project><groupId>org.example</groupId><artifactId>ok</artifactId><version>1.0.0</version></project>`,
+		"/org/example/broken/maven-metadata.xml": `<metadata><versioning><versions><version>1.0.0</version></versions></versioning></metadata>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	// cache a package for each dependency
+	home := t.TempDir()
+	t.Setenv("ESPRESSO_HOME", home)
+	reg := project.Registry{Name: "test", Url: server.URL, Type: "maven"}
+	cachePath, err := dependency.GetPackageCachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(cachePath, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jarPaths := []string{}
+	for _, name := range []string{"ok", "broken"} {
+		signature := registry.CalculatePackageSignature(reg, registry.Package{Group: "org.example", Name: name}, registry.PackageVersionDeclaration{Number: "1.0.0"})
+		jarPath := filepath.Join(cachePath, signature+".jar")
+		err = os.WriteFile(jarPath, []byte(name), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jarPaths = append(jarPaths, jarPath)
+	}
+
+	projectPath := t.TempDir()
+	err = os.WriteFile(filepath.Join(projectPath, "espresso.yml"), []byte(fmt.Sprintf(pruneProjectConfig, server.URL)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// prune, the broken dependency's package must survive
+	cmd := exec.Command(os.Args[0], "-test.run=^TestPruneCacheKeepsPackagesOfUnresolvedNodes$")
+	cmd.Dir = projectPath
+	cmd.Env = append(os.Environ(), "ESPRESSO_TEST_PRUNE_PROJECT="+projectPath)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected the prune to fail, got:\n%s", output)
+	}
+	for _, jarPath := range jarPaths {
+		if _, err := os.Stat(jarPath); err != nil {
+			t.Errorf("'%s' was pruned: %s\n%s", jarPath, err, output)
+		}
+	}
+}
@@ -14,7 +14,7 @@ import (
 	"kerosenelabs.com/espresso/core/util"
 )
 
-// SyncDependencies is a service function to iterate over each dependency and save it within the user's espresso cached packages
+// SyncDependencies is a service function to resolve the project's dependency graph and save each package within the user's espresso cached packages
 func SyncDependencies() {
 	// get our project context
 	projectContext, err := project.GetProjectContext()
@@ -22,21 +22,46 @@ func SyncDependencies() {
 		util.ErrorQuit("An error occurred while getting the project context: %s", err)
 	}
 
+	// record the checksums of newly added local jars, so any later change to them is caught when resolving
+	recorded, err := dependency.RecordLocalChecksums(&projectContext.Config)
+	if err != nil {
+		util.ErrorQuit("An error occurred while recording the checksums of local dependencies: %s", err)
+	}
+	if recorded {
+		err = project.Persist(*projectContext)
+		if err != nil {
+			util.ErrorQuit("An error occurred while persisting the configuration: %s", err)
+		}
+		color.Cyan("Recorded the checksums of local dependencies within espresso.yml")
+	}
+
+	// in offline mode we can only verify what's already cached
+	if util.IsOfflineMode() {
+		verifyCachedDependencies(projectContext.Config)
+		return
+	}
+
+	// resolve everything the project depends upon
+	color.Cyan("Resolving")
+	resolvedDependencies, err := dependency.ResolveProjectDependencies(projectContext.Config)
+	if err != nil {
+		util.ErrorQuit("An error occurred while resolving dependencies: %s", err)
+	}
+
 	// iterate over the dependencies
 	var wg sync.WaitGroup
-	for _, dep := range projectContext.Config.Dependencies {
+	for _, rdep := range resolvedDependencies {
+		displayStr := getResolvedDisplayString(rdep)
+		if rdep.IsLocal() {
+			color.Green("[%s] Found '%s' (%s)", displayStr, rdep.LocalPath, rdep.PackageVersion.Checksum)
+			continue
+		}
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			displayStr := fmt.Sprintf("%s:%s:%s", dep.Group, dep.Name, project.GetVersionAsString(dep.Version))
-			color.Cyan("[%s] Resolving", displayStr)
-			rdep, err := dependency.ResolveDependency(dep, projectContext.Config.Registries)
-			if err != nil {
-				util.ErrorQuit(fmt.Sprintf("[%s] An error occurred while resolving dependencies: %s\n", displayStr, err))
-			}
 
 			// cache the resolved dependency
-			err = dependency.CacheResolvedDependency(rdep)
+			err := dependency.CacheResolvedDependency(rdep)
 			if err != nil {
 				util.ErrorQuit(fmt.Sprintf("[%s] An error occurred while caching the resolved dependency: %s\n", displayStr, err))
 			}
@@ -45,3 +70,193 @@ func SyncDependencies() {
 	}
 	wg.Wait()
 }
+
+// verifyCachedDependencies ensures the project's dependency graph resolves from the cached registries and every
+// package within it is within the package cache, reporting each one that isn't
+func verifyCachedDependencies(cfg project.ProjectConfig) {
+	resolvedDependencies, err := dependency.ResolveProjectDependencies(cfg)
+	if err != nil {
+		util.ErrorQuit("Unresolvable from the cached registries: %s", err)
+	}
+
+	missing := 0
+	for _, rdep := range resolvedDependencies {
+		displayStr := getResolvedDisplayString(rdep)
+
+		// ensure the package is cached, along with its metadata
+		cachePath, err := rdep.GetCachePath()
+		if err != nil {
+			util.ErrorQuit("[%s] An error occurred while getting the cache path: %s", displayStr, err)
+		}
+		cached, err := dependency.IsResolvedDependencyCached(rdep)
+		if err != nil {
+			util.ErrorQuit("[%s] An error occurred while checking the cache: %s", displayStr, err)
+		}
+		if !cached {
+			color.Red("[%s] Missing from the package cache (expected at '%s')", displayStr, cachePath.Absolute)
+			missing++
+			continue
+		}
+		color.Green("[%s] Cached", displayStr)
+	}
+
+	if missing > 0 {
+		util.ErrorQuit("%d dependency package(s) are unavailable offline, run 'espresso dependency sync' while online to cache them", missing)
+	}
+}
+
+// getResolvedDisplayString gets the "group:name:version" of the resolved dependency
+func getResolvedDisplayString(rdep dependency.ResolvedDependency) string {
+	return fmt.Sprintf("%s:%s:%s", rdep.Package.Group, rdep.Package.Name, rdep.PackageVersion.Number)
+}
+
+// AddDependency is a service function that adds a "group:name[:version]" dependency to the project's configuration. If
+// the version is omitted, the latest version within the project's registries is used.
+func AddDependency(coordinate string) {
+	// get our project context
+	projectContext, err := project.GetProjectContext()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the project context: %s", err)
+	}
+
+	// parse the coordinate
+	group, name, number, err := dependency.ParseCoordinate(coordinate)
+	if err != nil {
+		util.ErrorQuit("%s", err)
+	}
+	for _, dep := range projectContext.Config.Dependencies {
+		if dep.Group == group && dep.Name == name {
+			util.ErrorQuit("'%s:%s' is already a dependency at '%s', use upgrade to change its version", group, name, project.GetVersionAsString(dep.Version))
+		}
+	}
+
+	// find the version to add, ensure it resolves
+	dep, err := getResolvableDependency(group, name, number, projectContext.Config.Registries)
+	if err != nil {
+		util.ErrorQuit("An error occurred while resolving '%s': %s", coordinate, err)
+	}
+
+	// persist it
+	projectContext.Config.Dependencies = append(projectContext.Config.Dependencies, dep)
+	err = project.Persist(*projectContext)
+	if err != nil {
+		util.ErrorQuit("An error occurred while persisting the configuration: %s", err)
+	}
+	color.Green("Added '%s:%s:%s'", group, name, project.GetVersionAsString(dep.Version))
+}
+
+// RemoveDependency is a service function that removes a "group:name" dependency from the project's configuration.
+func RemoveDependency(coordinate string) {
+	// get our project context
+	projectContext, err := project.GetProjectContext()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the project context: %s", err)
+	}
+
+	// parse the coordinate
+	group, name, _, err := dependency.ParseCoordinate(coordinate)
+	if err != nil {
+		util.ErrorQuit("%s", err)
+	}
+
+	// filter it out
+	deps := []project.Dependency{}
+	for _, dep := range projectContext.Config.Dependencies {
+		if dep.Group != group || dep.Name != name {
+			deps = append(deps, dep)
+		}
+	}
+	if len(deps) == len(projectContext.Config.Dependencies) {
+		util.ErrorQuit("'%s:%s' is not a dependency of this project", group, name)
+	}
+
+	// persist it
+	projectContext.Config.Dependencies = deps
+	err = project.Persist(*projectContext)
+	if err != nil {
+		util.ErrorQuit("An error occurred while persisting the configuration: %s", err)
+	}
+	color.Green("Removed '%s:%s'", group, name)
+}
+
+// UpgradeDependencies is a service function that upgrades the given "group:name[:version]" dependencies to the given
+// version, or the latest version within the project's registries if omitted. Every dependency is upgraded if no
+// coordinates are given.
+func UpgradeDependencies(coordinates []string) {
+	// get our project context
+	projectContext, err := project.GetProjectContext()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the project context: %s", err)
+	}
+
+	// figure out which dependencies we're upgrading and to what
+	targets := map[string]string{}
+	for _, coordinate := range coordinates {
+		group, name, number, err := dependency.ParseCoordinate(coordinate)
+		if err != nil {
+			util.ErrorQuit("%s", err)
+		}
+		targets[group+":"+name] = number
+	}
+	for key := range targets {
+		found := false
+		for _, dep := range projectContext.Config.Dependencies {
+			found = found || (!dep.IsLocal() && dep.Group+":"+dep.Name == key)
+		}
+		if !found {
+			util.ErrorQuit("'%s' is not a dependency of this project", key)
+		}
+	}
+
+	// upgrade each one
+	changed := false
+	for i, dep := range projectContext.Config.Dependencies {
+		number, ok := targets[dep.Group+":"+dep.Name]
+		if dep.IsLocal() || (len(targets) > 0 && !ok) {
+			continue
+		}
+		upgraded, err := getResolvableDependency(dep.Group, dep.Name, number, projectContext.Config.Registries)
+		if err != nil {
+			util.ErrorQuit("An error occurred while resolving '%s:%s': %s", dep.Group, dep.Name, err)
+		}
+		current, next := project.GetVersionAsString(dep.Version), project.GetVersionAsString(upgraded.Version)
+		if current == next {
+			color.Black("'%s:%s' is already at '%s'", dep.Group, dep.Name, current)
+			continue
+		}
+		projectContext.Config.Dependencies[i].Version = upgraded.Version
+		changed = true
+		color.Green("Upgraded '%s:%s' from '%s' to '%s'", dep.Group, dep.Name, current, next)
+	}
+
+	// persist it
+	if !changed {
+		return
+	}
+	err = project.Persist(*projectContext)
+	if err != nil {
+		util.ErrorQuit("An error occurred while persisting the configuration: %s", err)
+	}
+}
+
+// getResolvableDependency builds a dependency for the given version, or the latest version if empty, and ensures it
+// resolves within the given registries.
+func getResolvableDependency(group string, name string, number string, registries []project.Registry) (project.Dependency, error) {
+	var err error
+	if number == "" {
+		number, err = dependency.FindLatestVersion(group, name, registries)
+		if err != nil {
+			return project.Dependency{}, err
+		}
+	}
+	version, err := project.ParseVersion(number)
+	if err != nil {
+		return project.Dependency{}, err
+	}
+	dep := project.Dependency{Group: group, Name: name, Version: version}
+	_, err = dependency.ResolveDependency(dep, registries)
+	if err != nil {
+		return project.Dependency{}, err
+	}
+	return dep, nil
+}
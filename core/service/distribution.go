@@ -0,0 +1,35 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package service
+
+import (
+	"github.com/fatih/color"
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/toolchain"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// PackageDistribution is a service function that creates the distribution archives of the built application, ready to
+// be unpacked and run wherever a JDK is installed
+func PackageDistribution() {
+	// get our project context
+	projectContext, err := project.GetProjectContext()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the project context: %s", err)
+	}
+	if projectContext.Config.IsLibrary() {
+		util.ErrorQuit("A distribution can only be created for an application, '%s' is a library", projectContext.Config.Name)
+	}
+
+	color.Cyan("- Packaging the distribution of '%s'", projectContext.Config.Name)
+	archives, err := toolchain.PackageDistribution(projectContext.Config)
+	if err != nil {
+		util.ErrorQuit("An error occurred while packaging the distribution: %s", err)
+	}
+	for _, archive := range archives {
+		color.Black("-- Packaged: %s", archive)
+	}
+	color.Green("- Done!")
+}
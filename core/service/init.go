@@ -8,15 +8,19 @@ import (
 	"fmt"
 
 	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/toolchain"
 	"kerosenelabs.com/espresso/core/util"
 )
 
 // InitializeProject is a service function for initializing a new project
 func InitializeProject(name string, basePkg *string) {
-	// ensure JAVA_HOME is set
-	javaHome, err := util.GetJavaHome()
+	// require the version of the JDK within JAVA_HOME, or the newest one installed
+	installation, err := getDefaultJavaInstallation()
 	if err != nil {
-		util.ErrorQuit("JAVA_HOME is not set; do you have java installed?")
+		util.ErrorQuit("An error occurred while finding a JDK: %s", err)
+	}
+	if installation == nil {
+		util.ErrorQuit("No JDK was found within JAVA_HOME or the usual install locations; do you have java installed?")
 	}
 
 	// ensure a proejct doesn't already exist
@@ -42,7 +46,7 @@ func InitializeProject(name string, basePkg *string) {
 		},
 		BasePackage: *basePkg,
 		Toolchain: project.Toolchain{
-			Path: *javaHome,
+			Version: installation.GetFeatureVersion(),
 		},
 		Registries:   []project.Registry{{Name: "espresso-registry", Url: "https://github.com/Kerosene-Labs/espresso-registry/archive/refs/heads/main.zip"}},
 		Dependencies: []project.Dependency{},
@@ -83,3 +87,17 @@ func InitializeProject(name string, basePkg *string) {
 
 	println("Done.")
 }
+
+// getDefaultJavaInstallation gets the JDK within JAVA_HOME, otherwise the newest one on this machine, or nil
+func getDefaultJavaInstallation() (*toolchain.JavaInstallation, error) {
+	if javaHome, err := util.GetJavaHome(); err == nil {
+		if installation, err := toolchain.ReadJavaInstallation(*javaHome, "JAVA_HOME"); err == nil {
+			return installation, nil
+		}
+	}
+	installations, err := toolchain.RefreshJavaInstallations()
+	if err != nil || len(installations) == 0 {
+		return nil, err
+	}
+	return &installations[0], nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package service
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/toolchain"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// LinkRuntime is a service function that creates a trimmed runtime image of the built application in dist/runtime,
+// holding only the JDK modules it needs. The given options override the project's jlink configuration where set.
+func LinkRuntime(options project.JlinkConfig) {
+	// get our project context
+	projectContext, err := project.GetProjectContext()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the project context: %s", err)
+	}
+	if projectContext.Config.IsLibrary() {
+		util.ErrorQuit("A runtime image can only be created for an application, '%s' is a library", projectContext.Config.Name)
+	}
+
+	// the flags take precedence over the config
+	config := projectContext.Config.Jlink
+	if options.Compress != "" {
+		config.Compress = options.Compress
+	}
+	if options.StripDebug {
+		config.StripDebug = true
+	}
+	config.Modules = append(config.Modules, options.Modules...)
+
+	// find what the application needs
+	color.Cyan("- Finding the JDK modules required by '%s'", projectContext.Config.Name)
+	jars, err := toolchain.GetDistributedJars(projectContext.Config)
+	if err != nil {
+		util.ErrorQuit("An error occurred while finding the distributed jars: %s", err)
+	}
+	modules, err := toolchain.FindRequiredJavaModules(projectContext.Config, jars)
+	if err != nil {
+		util.ErrorQuit("An error occurred while running jdeps: %s", err)
+	}
+	color.Black("-- Required: %s", strings.Join(modules, ", "))
+	if len(config.Modules) > 0 {
+		color.Black("-- Added: %s", strings.Join(config.Modules, ", "))
+	}
+
+	// link it
+	color.Cyan("- Linking the runtime image")
+	launcherPath, err := toolchain.LinkRuntime(projectContext.Config, modules, config)
+	if err != nil {
+		util.ErrorQuit("An error occurred while running jlink: %s", err)
+	}
+	color.Black("-- Launcher: %s", launcherPath)
+	color.Green("- Done!")
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package service
+
+import (
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/registry"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// outdatedDependency is the JSON output format of a single dependency within ReportOutdatedDependencies
+type outdatedDependency struct {
+	Group       string   `json:"group"`
+	Name        string   `json:"name"`
+	Current     string   `json:"current"`
+	LatestPatch string   `json:"latestPatch,omitempty"`
+	LatestMinor string   `json:"latestMinor,omitempty"`
+	LatestMajor string   `json:"latestMajor,omitempty"`
+	Registries  []string `json:"registries"`
+	Missing     bool     `json:"missing"`
+	Outdated    bool     `json:"outdated"`
+}
+
+// ReportOutdatedDependencies is a service function that compares each of the project's dependencies against every
+// version within the project's registries, printing the newest patch, minor and major versions available.
+func ReportOutdatedDependencies(output string) {
+	if output != "table" && output != "json" {
+		util.ErrorQuit("Unknown output format '%s'", output)
+	}
+
+	// get our project context
+	projectContext, err := project.GetProjectContext()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the project context: %s", err)
+	}
+
+	// compare each dependency against every registry
+	report := []outdatedDependency{}
+	for _, dep := range projectContext.Config.Dependencies {
+		if dep.IsLocal() {
+			continue
+		}
+		current := project.GetVersionAsString(dep.Version)
+		entry := outdatedDependency{Group: dep.Group, Name: dep.Name, Current: current, Registries: []string{}}
+		for _, reg := range projectContext.Config.Registries {
+			pkg, err := registry.FindPackage(reg, dep.Group, dep.Name)
+			if err != nil {
+				util.ErrorQuit("An error occurred while searching the '%s' registry: %s", reg.Name, err)
+			}
+			if pkg == nil {
+				continue
+			}
+			entry.Registries = append(entry.Registries, reg.Name)
+			for _, version := range pkg.Versions {
+				updateOutdatedDependency(&entry, version.Number)
+			}
+		}
+		entry.Missing = len(entry.Registries) == 0
+		entry.Outdated = entry.LatestPatch != "" || entry.LatestMinor != "" || entry.LatestMajor != ""
+		report = append(report, entry)
+	}
+
+	// print the report
+	if output == "json" {
+		printJson(report)
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Group", "Package", "Current", "Patch", "Minor", "Major"})
+	for _, entry := range report {
+		row := []string{entry.Group, entry.Name, entry.Current, orDash(entry.LatestPatch), orDash(entry.LatestMinor), orDash(entry.LatestMajor)}
+		switch {
+		case entry.Missing:
+			row[2] = color.RedString("%s (not in any registry)", entry.Current)
+		case entry.Outdated:
+			row[2] = color.YellowString("%s", entry.Current)
+		}
+		table.Append(row)
+	}
+	table.Render()
+}
+
+// updateOutdatedDependency records the version as the entry's latest patch, minor or major if it's newer than both the
+// current version and the one already recorded
+func updateOutdatedDependency(entry *outdatedDependency, number string) {
+	if registry.CompareVersionNumbers(number, entry.Current) <= 0 {
+		return
+	}
+	major, minor, _ := registry.GetVersionNumberComponents(number)
+	currentMajor, currentMinor, _ := registry.GetVersionNumberComponents(entry.Current)
+
+	var latest *string
+	switch {
+	case major != currentMajor:
+		latest = &entry.LatestMajor
+	case minor != currentMinor:
+		latest = &entry.LatestMinor
+	default:
+		latest = &entry.LatestPatch
+	}
+	if *latest == "" || registry.CompareVersionNumbers(number, *latest) > 0 {
+		*latest = number
+	}
+}
+
+// orDash returns the value, or "-" if it's empty
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,23 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package service
+
+import (
+	"encoding/json"
+	"os"
+
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// printJson prints the given value to the standard output as indented JSON, for consumption by other tools
+func printJson(value any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(value)
+	if err != nil {
+		util.ErrorQuit("An error occurred while marshalling the output: %s", err)
+	}
+}
@@ -6,7 +6,9 @@ package service
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 
@@ -14,56 +16,213 @@ import (
 	"github.com/olekukonko/tablewriter"
 	"kerosenelabs.com/espresso/core/context/project"
 	"kerosenelabs.com/espresso/core/registry"
+	"kerosenelabs.com/espresso/core/toolchain"
 	"kerosenelabs.com/espresso/core/util"
 )
 
+// QueryOptions configures the filtering and output of QueryRegistries
+type QueryOptions struct {
+	Group     string
+	Registry  string
+	Processor bool
+	Output    string
+}
+
+// queryResult is the JSON output format of a single QueryRegistries result
+type queryResult struct {
+	Group                 string `json:"group"`
+	Name                  string `json:"name"`
+	Description           string `json:"description"`
+	LatestVersion         string `json:"latestVersion,omitempty"`
+	Registry              string `json:"registry"`
+	IsAnnotationProcessor bool   `json:"isAnnotationProcessor"`
+	Score                 int    `json:"score"`
+}
+
 // QueryRegistries is a service function for querying all registries declared within a project
-func QueryRegistries(term string) {
+func QueryRegistries(term string, opts QueryOptions) {
 	// get our project context
 	projectContext, err := project.GetProjectContext()
 	if err != nil {
 		util.ErrorQuit("An error occurred while getting the project context: %s", err)
 	}
+	if opts.Output != "table" && opts.Output != "json" {
+		util.ErrorQuit("Unknown output format '%s'", opts.Output)
+	}
 
 	// iterate over each registry, get its packages
-	var filteredPkgs []registry.Package = []registry.Package{}
+	var pkgs []registry.Package = []registry.Package{}
 	for _, reg := range projectContext.Config.Registries {
-		color.Blue("Checking '%s'", reg.Name)
+		if opts.Registry != "" && reg.Name != opts.Registry {
+			continue
+		}
+		if opts.Output == "table" {
+			color.Blue("Checking '%s'", reg.Name)
+		}
 		regPkgs, err := registry.GetRegistryPackages(reg)
 		if err != nil {
 			util.ErrorQuit("An error occurred while fetching packages from the '%s' registry cache: %s", reg.Name, err)
 		}
+		pkgs = append(pkgs, regPkgs...)
+	}
+
+	// rank our packages
+	results := registry.SearchPackages(pkgs, term, registry.SearchFilter{
+		Group:     opts.Group,
+		Registry:  opts.Registry,
+		Processor: opts.Processor,
+	})
 
-		// filter by name
-		for _, pkg := range regPkgs {
-			if term == "*" ||
-				strings.Contains(strings.ToLower(pkg.Name), strings.ToLower(term)) ||
-				strings.Contains(strings.ToLower(pkg.Description), strings.ToLower(term)) {
-				filteredPkgs = append(filteredPkgs, pkg)
+	// print out our packages
+	if opts.Output == "json" {
+		out := []queryResult{}
+		for _, result := range results {
+			latest := ""
+			if version := registry.GetLatestPackageVersion(result.Package); version != nil {
+				latest = version.Number
 			}
+			out = append(out, queryResult{
+				Group:                 result.Package.Group,
+				Name:                  result.Package.Name,
+				Description:           result.Package.Description,
+				LatestVersion:         latest,
+				Registry:              result.Package.Registry.Name,
+				IsAnnotationProcessor: registry.IsAnnotationProcessor(result.Package),
+				Score:                 result.Score,
+			})
 		}
+		printJson(out)
+		return
 	}
 
-	// print out our packages
-	color.Cyan("Found %v package(s):", len(filteredPkgs))
-	data := [][]string{}
-	for _, filtered := range filteredPkgs {
-		data = append(data, []string{
-			filtered.Group,
-			filtered.Name,
-			filtered.Versions[len(filtered.Versions)-1].Number,
+	color.Cyan("Found %v package(s):", len(results))
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Group", "Package", "Latest Version", "Registry", "Description"})
+	for _, result := range results {
+		latest := "-"
+		if version := registry.GetLatestPackageVersion(result.Package); version != nil {
+			latest = version.Number
+		}
+		table.Append([]string{
+			result.Package.Group,
+			result.Package.Name,
+			latest,
+			result.Package.Registry.Name,
+			result.Package.Description,
 		})
 	}
+	table.Render()
+}
 
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Group", "Package", "Latest Version"})
+// packageVersionDetail is the JSON output format of a single version within ShowPackage
+type packageVersionDetail struct {
+	Number                string   `json:"number"`
+	Registry              string   `json:"registry"`
+	ShadowedRegistries    []string `json:"shadowedRegistries"`
+	TransientDependencies []string `json:"transientDependencies"`
+	IsAnnotationProcessor bool     `json:"isAnnotationProcessor"`
+	ArtifactUrl           string   `json:"artifactUrl"`
+}
 
-	for _, v := range data {
-		table.Append(v)
+// ShowPackage is a service function that prints every version of a package across the project's registries, along
+// with the registry that would win resolution for each version.
+func ShowPackage(coordinate string, output string) {
+	// get our project context
+	projectContext, err := project.GetProjectContext()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the project context: %s", err)
+	}
+	if output != "table" && output != "json" {
+		util.ErrorQuit("Unknown output format '%s'", output)
+	}
+	group, name, found := strings.Cut(coordinate, ":")
+	if !found || group == "" || name == "" {
+		util.ErrorQuit("'%s' must be in the group:name format", coordinate)
+	}
+
+	// registries are searched in order, so the first registry to declare a version wins it
+	details := []*packageVersionDetail{}
+	description := ""
+	for _, reg := range projectContext.Config.Registries {
+		pkg, err := registry.FindPackage(reg, group, name)
+		if err != nil {
+			util.ErrorQuit("An error occurred while searching the '%s' registry: %s", reg.Name, err)
+		}
+		if pkg == nil {
+			continue
+		}
+		if description == "" {
+			description = pkg.Description
+		}
+		for _, version := range pkg.Versions {
+			// equivalent numbers (ex: "1.0" and "1.0.0") are the same version to resolution
+			if existing := findPackageVersionDetail(details, version.Number); existing != nil {
+				shadowed := reg.Name
+				if existing.Number != version.Number {
+					shadowed += " (" + version.Number + ")"
+				}
+				existing.ShadowedRegistries = append(existing.ShadowedRegistries, shadowed)
+				continue
+			}
+			detail := &packageVersionDetail{
+				Number:                version.Number,
+				Registry:              reg.Name,
+				ShadowedRegistries:    []string{},
+				TransientDependencies: version.TransientDependencies,
+				IsAnnotationProcessor: version.IsAnnotationProcessor,
+				ArtifactUrl:           version.ArtifactUrl,
+			}
+			if detail.TransientDependencies == nil {
+				detail.TransientDependencies = []string{}
+			}
+			details = append(details, detail)
+		}
+	}
+	if len(details) == 0 {
+		util.ErrorQuit("'%s' was not found within any registry", coordinate)
+	}
+	sort.SliceStable(details, func(i, j int) bool {
+		return registry.CompareVersionNumbers(details[i].Number, details[j].Number) > 0
+	})
+
+	// print it
+	if output == "json" {
+		printJson(map[string]any{
+			"group":       group,
+			"name":        name,
+			"description": description,
+			"versions":    details,
+		})
+		return
+	}
+	color.Cyan("%s:%s", group, name)
+	if description != "" {
+		fmt.Println(description)
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Version", "Registry", "Shadowed In", "Transient Dependencies", "Processor"})
+	for _, detail := range details {
+		table.Append([]string{
+			detail.Number,
+			detail.Registry,
+			strings.Join(detail.ShadowedRegistries, ", "),
+			strings.Join(detail.TransientDependencies, ", "),
+			fmt.Sprintf("%t", detail.IsAnnotationProcessor),
+		})
 	}
 	table.Render()
 }
 
+// findPackageVersionDetail finds the detail of a version equivalent to the given number, or nil
+func findPackageVersionDetail(details []*packageVersionDetail, number string) *packageVersionDetail {
+	for _, detail := range details {
+		if registry.CompareVersionNumbers(detail.Number, number) == 0 {
+			return detail
+		}
+	}
+	return nil
+}
+
 // InvalidateRegistryCaches is a service function that invalidates and recaches all registries declared within the project.
 func InvalidateRegistryCaches() {
 	// get our project context
@@ -72,35 +231,141 @@ func InvalidateRegistryCaches() {
 		util.ErrorQuit("An error occurred while getting the project context: %s", err)
 	}
 
-	// iterate over each registry, invalidate it
-	var invalWg sync.WaitGroup
-	for _, reg := range projectContext.Config.Registries {
-		invalWg.Add(1)
-		go func() {
-			defer invalWg.Done()
-			color.Cyan("[%s] Invalidating cache", reg.Name)
-			err = registry.InvalidateRegistryCache(reg)
-			if err != nil {
-				util.ErrorQuit("An error occurred while invalidaing cache(s): %s", err)
-			}
-			color.Blue("[%s] Invalidated", reg.Name)
-		}()
+	// we'd be unable to recache what we invalidate
+	if util.IsOfflineMode() {
+		util.ErrorQuit("Registries can't be invalidated in offline mode")
 	}
-	invalWg.Wait()
 
-	// iterate over each registry, download the zip
+	// iterate over each registry, download a fresh cache and swap it in for the old one
 	var dlWg sync.WaitGroup
 	for _, reg := range projectContext.Config.Registries {
 		dlWg.Add(1)
 		go func() {
 			defer dlWg.Done()
 			color.Cyan("[%s] Downloading archive", reg.Name)
-			err = registry.CacheRegistry(reg)
+			err := registry.RefreshRegistryCache(reg)
 			if err != nil {
-				util.ErrorQuit(fmt.Sprintf("An error occurred while downloading the registry archive: %s\n", err))
+				util.ErrorQuit(fmt.Sprintf("An error occurred while recaching the registry: %s\n", err))
 			}
 			color.Blue("[%s] Finished caching", reg.Name)
 		}()
 	}
 	dlWg.Wait()
 }
+
+// PublishPackage is a service function that writes a version of a jar into a package declaration within a local
+// registry checkout.
+func PublishPackage(registryPath string, group string, name string, version string, jarPath string, artifactUrl string, dependencies []string, isAnnotationProcessor bool, description string) {
+	// validate our input
+	if group == "" || name == "" || version == "" {
+		util.ErrorQuit("A group, name and version are required to publish a package")
+	}
+	if _, err := url.ParseRequestURI(artifactUrl); err != nil {
+		util.ErrorQuit("The artifact url '%s' is invalid: %s", artifactUrl, err)
+	}
+	for _, dep := range dependencies {
+		if len(strings.Split(dep, ":")) != 3 {
+			util.ErrorQuit("The transient dependency '%s' must be in the group:name:version format", dep)
+		}
+	}
+
+	// compute the jar's checksum
+	digest, err := util.HashFile(jarPath, "sha256")
+	if err != nil {
+		util.ErrorQuit("An error occurred while computing the checksum of '%s': %s", jarPath, err)
+	}
+
+	// write the declaration
+	declPath, err := registry.PublishPackageVersion(registryPath, group, name, description, registry.PackageVersionDeclaration{
+		Number:                version,
+		ArtifactUrl:           artifactUrl,
+		TransientDependencies: dependencies,
+		IsAnnotationProcessor: isAnnotationProcessor,
+		Checksum:              "sha256:" + digest,
+	})
+	if err != nil {
+		util.ErrorQuit("An error occurred while publishing the package: %s", err)
+	}
+	color.Green("Published '%s:%s:%s' to '%s'", group, name, version, declPath)
+}
+
+// PublishProject is a service function that publishes the current project's distributable as a package within a local
+// registry checkout. The group and name default to the project's base package and name.
+func PublishProject(registryPath string, group string, name string, artifactUrl string, description string) {
+	// get our project context
+	projectContext, err := project.GetProjectContext()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the project context: %s", err)
+	}
+	cfg := projectContext.Config
+
+	// get our distributable
+	jarPath, err := toolchain.GetJarPath(cfg)
+	if err != nil {
+		util.ErrorQuit("Unable to get dist path: %s", err)
+	}
+	exists, err := util.DoesPathExist(jarPath)
+	if err != nil || !exists {
+		util.ErrorQuit("'%s' does not exist, please build the project first", jarPath)
+	}
+
+	// the project's dependencies are the package's transient dependencies, local jars can't be depended upon
+	dependencies := []string{}
+	for _, dep := range cfg.Dependencies {
+		if dep.IsLocal() {
+			color.Yellow("The local dependency '%s' will not be declared by the published package", dep.Path)
+			continue
+		}
+		dependencies = append(dependencies, fmt.Sprintf("%s:%s:%s", dep.Group, dep.Name, project.GetVersionAsString(dep.Version)))
+	}
+
+	if group == "" {
+		group = cfg.BasePackage
+	}
+	if name == "" {
+		name = cfg.Name
+	}
+	PublishPackage(registryPath, group, name, project.GetVersionAsString(cfg.Version), jarPath, artifactUrl, dependencies, false, description)
+}
+
+// LintRegistry is a service function that validates a local registry checkout, printing the issues in the given
+// format ("text" or "json"). Exits non-zero if any errors were found.
+func LintRegistry(registryPath string, format string, requireChecksums bool) {
+	issues, err := registry.LintRegistry(registryPath, registry.LintOptions{RequireChecksums: requireChecksums})
+	if err != nil {
+		util.ErrorQuit("An error occurred while linting the registry: %s", err)
+	}
+
+	// count our errors
+	errorCount := 0
+	for _, issue := range issues {
+		if issue.Severity == registry.LintSeverityError {
+			errorCount++
+		}
+	}
+
+	// print the issues
+	switch format {
+	case "json":
+		printJson(issues)
+	case "text":
+		for _, issue := range issues {
+			line := fmt.Sprintf("%s: %s", issue.Path, issue.Message)
+			if issue.Version != "" {
+				line = fmt.Sprintf("%s (%s): %s", issue.Path, issue.Version, issue.Message)
+			}
+			if issue.Severity == registry.LintSeverityError {
+				color.Red("error   %s", line)
+			} else {
+				color.Yellow("warning %s", line)
+			}
+		}
+		color.Cyan("%d error(s), %d warning(s)", errorCount, len(issues)-errorCount)
+	default:
+		util.ErrorQuit("Unknown output format '%s'", format)
+	}
+
+	if errorCount > 0 {
+		os.Exit(1)
+	}
+}
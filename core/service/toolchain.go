@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package service
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/toolchain"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// toolchainOutput is a JDK as printed by "toolchain list -o json"
+type toolchainOutput struct {
+	toolchain.JavaInstallation
+	FeatureVersion int  `json:"featureVersion"`
+	Selected       bool `json:"selected"`
+}
+
+// ListToolchains is a service function that discovers the JDKs on this machine and prints them, marking the one the
+// current project (if any) uses
+func ListToolchains(output string) {
+	if output != "table" && output != "json" {
+		util.ErrorQuit("Unknown output format '%s'", output)
+	}
+	installations, err := toolchain.RefreshJavaInstallations()
+	if err != nil {
+		util.ErrorQuit("An error occurred while discovering toolchains: %s", err)
+	}
+
+	// the project's requirement may be unsatisfied, that's not an error here
+	selected := ""
+	if projectContext, err := project.GetProjectContext(); err == nil {
+		selected, _ = toolchain.ResolveToolchainPath(projectContext.Config)
+	}
+
+	if output == "json" {
+		out := []toolchainOutput{}
+		for _, installation := range installations {
+			out = append(out, toolchainOutput{
+				JavaInstallation: installation,
+				FeatureVersion:   installation.GetFeatureVersion(),
+				Selected:         installation.Path == selected,
+			})
+		}
+		printJson(out)
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"", "Version", "Vendor", "Source", "Path"})
+	for _, installation := range installations {
+		marker := ""
+		if installation.Path == selected {
+			marker = "*"
+		}
+		vendor := installation.Vendor
+		if installation.VendorVersion != "" {
+			vendor += " (" + installation.VendorVersion + ")"
+		}
+		table.Append([]string{marker, installation.Version, vendor, installation.Source, installation.Path})
+	}
+	table.Render()
+	toolchainsPath, err := toolchain.GetToolchainsPath()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the toolchains path: %s", err)
+	}
+	color.Cyan("%d toolchain(s), stored in '%s'", len(installations), toolchainsPath)
+}
+
+// AddToolchain is a service function that adds a JDK installed outside the discovered locations to this machine's
+// toolchains
+func AddToolchain(path string) {
+	installation, err := toolchain.AddJavaInstallation(path)
+	if err != nil {
+		util.ErrorQuit("An error occurred while adding the toolchain: %s", err)
+	}
+	color.Green("Added JDK %s (%s) at '%s', projects may now require version %d", installation.Version, installation.Vendor, installation.Path, installation.GetFeatureVersion())
+}
+
+// WarnLegacyToolchain is a service function that warns when the current project (if any) requires its toolchain by a
+// path only meaningful on this machine, suggesting the version of the JDK found there. Printed to the standard error
+// so JSON output stays parsable.
+func WarnLegacyToolchain() {
+	configPath, err := project.GetConfigPath()
+	if err != nil {
+		return
+	}
+	if exists, _ := util.DoesPathExist(configPath); !exists {
+		return
+	}
+
+	// read the config without loading the project, the command reports whether it's valid
+	cfg, err := project.ReadConfig(configPath)
+	if err != nil || cfg.Toolchain.Version != 0 || cfg.Toolchain.Path == "" {
+		return
+	}
+	suggestion := "'version: <feature version>'"
+	if release, err := toolchain.ReadJavaRelease(cfg.Toolchain.Path); err == nil && toolchain.GetJavaFeatureVersion(release) != 0 {
+		suggestion = fmt.Sprintf("'version: %d'", toolchain.GetJavaFeatureVersion(release))
+	}
+	fmt.Fprintln(os.Stderr, color.YellowString("The project's toolchain path '%s' only exists on this machine, "+
+		"replace it with %s under toolchain within espresso.yml", cfg.Toolchain.Path, suggestion))
+}
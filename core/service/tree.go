@@ -0,0 +1,244 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/dependency"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// treeNode is the JSON output format of a package within the dependency tree
+type treeNode struct {
+	Group        string      `json:"group"`
+	Name         string      `json:"name"`
+	Requested    string      `json:"requested"`
+	Version      string      `json:"version"`
+	Registry     string      `json:"registry,omitempty"`
+	Scope        string      `json:"scope"`
+	Conflict     bool        `json:"conflict"`
+	Overridden   bool        `json:"overridden,omitempty"`
+	Repeated     bool        `json:"repeated,omitempty"`
+	Cycle        bool        `json:"cycle,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	Dependencies []*treeNode `json:"dependencies,omitempty"`
+}
+
+// PrintDependencyTree is a service function that prints the project's resolved dependency graph in the given format
+// ("tree", "json" or "dot").
+func PrintDependencyTree(output string) {
+	graph := getProjectDependencyGraph(output)
+	switch output {
+	case "tree":
+		for _, root := range buildTree(graph) {
+			printTreeNode(root, "", "")
+		}
+	case "json":
+		printJson(buildTree(graph))
+	case "dot":
+		printDot(graph, nil)
+	}
+}
+
+// PrintDependencyPaths is a service function that prints every path from the project's direct dependencies to the
+// given "group:name" package in the given format ("tree", "json" or "dot").
+func PrintDependencyPaths(coordinate string, output string) {
+	group, name, _, err := dependency.ParseCoordinate(coordinate)
+	if err != nil {
+		util.ErrorQuit("%s", err)
+	}
+	key := dependency.GetDependencyKey(group, name)
+	graph := getProjectDependencyGraph(output)
+	if _, ok := graph.Nodes[key]; !ok {
+		util.ErrorQuit("'%s' is not within the project's dependency graph", key)
+	}
+	paths := graph.GetPaths(key)
+
+	switch output {
+	case "tree":
+		color.Cyan("%d path(s) to '%s':", len(paths), graph.Nodes[key].Coordinate())
+		for _, path := range paths {
+			labels := []string{}
+			for _, edge := range path {
+				labels = append(labels, describeEdge(graph, edge))
+			}
+			fmt.Println(strings.Join(labels, " -> "))
+		}
+	case "json":
+		out := [][]*treeNode{}
+		for _, path := range paths {
+			nodes := []*treeNode{}
+			for _, edge := range path {
+				nodes = append(nodes, newTreeNode(graph, edge))
+			}
+			out = append(out, nodes)
+		}
+		printJson(out)
+	case "dot":
+		printDot(graph, paths)
+	}
+}
+
+// getProjectDependencyGraph resolves the current project's dependency graph
+func getProjectDependencyGraph(output string) *dependency.DependencyGraph {
+	if output != "tree" && output != "json" && output != "dot" {
+		util.ErrorQuit("Unknown output format '%s'", output)
+	}
+
+	// get our project context
+	projectContext, err := project.GetProjectContext()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the project context: %s", err)
+	}
+
+	// resolve the graph
+	graph, err := dependency.ResolveDependencyGraph(projectContext.Config)
+	if err != nil {
+		util.ErrorQuit("An error occurred while resolving the dependency graph: %s", err)
+	}
+	return graph
+}
+
+// newTreeNode creates the output node for the given edge, without its dependencies
+func newTreeNode(graph *dependency.DependencyGraph, edge dependency.DependencyEdge) *treeNode {
+	node := graph.Nodes[edge.Key]
+	out := &treeNode{
+		Group:      node.Group,
+		Name:       node.Name,
+		Requested:  edge.Requested,
+		Version:    node.Version,
+		Scope:      node.Scope(),
+		Conflict:   graph.IsConflict(edge),
+		Overridden: node.Overridden,
+	}
+	if node.Resolved != nil {
+		out.Registry = node.Resolved.Registry.Name
+	}
+	if node.Err != nil {
+		out.Error = node.Err.Error()
+	}
+	return out
+}
+
+// buildTree expands the graph into a tree. A package's dependencies are only expanded the first time it appears, later
+// appearances are marked as repeated, and edges back to an ancestor are marked as cycles.
+func buildTree(graph *dependency.DependencyGraph) []*treeNode {
+	expanded := map[string]bool{}
+	var build func(edge dependency.DependencyEdge, ancestors map[string]bool) *treeNode
+	build = func(edge dependency.DependencyEdge, ancestors map[string]bool) *treeNode {
+		out := newTreeNode(graph, edge)
+		switch {
+		case ancestors[edge.Key]:
+			out.Cycle = true
+		case expanded[edge.Key]:
+			out.Repeated = len(graph.Nodes[edge.Key].Children) > 0
+		default:
+			expanded[edge.Key] = true
+			ancestors[edge.Key] = true
+			for _, child := range graph.Nodes[edge.Key].Children {
+				out.Dependencies = append(out.Dependencies, build(child, ancestors))
+			}
+			delete(ancestors, edge.Key)
+		}
+		return out
+	}
+
+	roots := []*treeNode{}
+	for _, root := range graph.Roots {
+		roots = append(roots, build(root, map[string]bool{}))
+	}
+	return roots
+}
+
+// printTreeNode prints the node and its dependencies as an indented tree
+func printTreeNode(node *treeNode, prefix string, childPrefix string) {
+	label := fmt.Sprintf("%s:%s:%s", node.Group, node.Name, node.Requested)
+	switch {
+	case node.Conflict && node.Overridden:
+		label += color.CyanString(" -> %s (overridden)", node.Version)
+	case node.Conflict:
+		label += color.YellowString(" -> %s (conflict)", node.Version)
+	}
+	if node.Registry != "" {
+		label += fmt.Sprintf(" (%s)", node.Registry)
+	}
+	label += fmt.Sprintf(" [%s]", node.Scope)
+	switch {
+	case node.Error != "":
+		label += color.RedString(" (unresolved: %s)", node.Error)
+	case node.Cycle:
+		label += color.RedString(" (cycle)")
+	case node.Repeated:
+		label += " (*)"
+	}
+	fmt.Println(prefix + label)
+
+	for i, child := range node.Dependencies {
+		if i == len(node.Dependencies)-1 {
+			printTreeNode(child, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			printTreeNode(child, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+// describeEdge gets a one line description of an edge (ex: org.slf4j:slf4j-api:1.7.0 -> 2.0.0)
+func describeEdge(graph *dependency.DependencyGraph, edge dependency.DependencyEdge) string {
+	node := graph.Nodes[edge.Key]
+	label := edge.Key + ":" + edge.Requested
+	if graph.IsConflict(edge) {
+		label += " -> " + node.Version
+	}
+	return label
+}
+
+// printDot prints the graph in the Graphviz DOT format. If paths are given, only the edges along them are printed.
+func printDot(graph *dependency.DependencyGraph, paths [][]dependency.DependencyEdge) {
+	lines := []string{}
+	seen := map[string]bool{}
+	addEdge := func(from string, edge dependency.DependencyEdge) {
+		attributes := ""
+		if graph.IsConflict(edge) {
+			attributes = fmt.Sprintf(" [label=%q, color=red]", "requested "+edge.Requested)
+		}
+		line := fmt.Sprintf("  %q -> %q%s;", from, graph.Nodes[edge.Key].Coordinate(), attributes)
+		if !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+
+	if paths == nil {
+		for _, root := range graph.Roots {
+			addEdge("project", root)
+		}
+		for _, node := range graph.Nodes {
+			for _, child := range node.Children {
+				addEdge(node.Coordinate(), child)
+			}
+		}
+	} else {
+		for _, path := range paths {
+			from := "project"
+			for _, edge := range path {
+				addEdge(from, edge)
+				from = graph.Nodes[edge.Key].Coordinate()
+			}
+		}
+	}
+	sort.Strings(lines)
+
+	fmt.Println("digraph dependencies {")
+	fmt.Println("  \"project\" [shape=box];")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	fmt.Println("}")
+}
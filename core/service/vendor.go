@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package service
+
+import (
+	"github.com/fatih/color"
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/dependency"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// VendorDependencies is a service function that caches every dependency of the project and copies it into the
+// project's vendor directory, along with a manifest of what was vendored.
+func VendorDependencies() {
+	// get our project context
+	projectContext, err := project.GetProjectContext()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the project context: %s", err)
+	}
+	if util.IsVendorMode() {
+		util.ErrorQuit("The project can't be vendored from its own vendor directory, unset ESPRESSO_VENDOR")
+	}
+
+	// resolve and cache everything we're vendoring
+	resolvedDependencies, err := dependency.ResolveProjectDependencies(projectContext.Config)
+	if err != nil {
+		util.ErrorQuit("An error occurred while resolving dependencies: %s", err)
+	}
+	for _, resolved := range resolvedDependencies {
+		err = dependency.CacheResolvedDependency(resolved)
+		if err != nil {
+			util.ErrorQuit("[%s:%s] An error occurred while caching the resolved dependency: %s", resolved.Package.Group, resolved.Package.Name, err)
+		}
+	}
+
+	// vendor it
+	manifest, err := dependency.VendorDependencies(resolvedDependencies)
+	if err != nil {
+		util.ErrorQuit("An error occurred while vendoring dependencies: %s", err)
+	}
+	for _, pkg := range manifest.Packages {
+		color.Green("[%s] Vendored to 'vendor/%s'", pkg.Coordinate(), pkg.File)
+	}
+	vendorPath, err := dependency.GetVendorPath()
+	if err != nil {
+		util.ErrorQuit("An error occurred while getting the vendor path: %s", err)
+	}
+	color.Cyan("Vendored %d package(s) into '%s', build with --vendor to use them", len(manifest.Packages), vendorPath)
+}
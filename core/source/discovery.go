@@ -5,50 +5,127 @@
 package source
 
 import (
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/util"
 )
 
 // SourceFIle represents a .java source file on the filesystem
 type SourceFile struct {
 	Path    string
 	Content string
+	// Root is the source root the file was found within
+	Root SourceRoot
 }
 
-// DiscoverSourceFiles iterates over the project's base package looking for .java files
+var (
+	// commentPattern matches Java block and line comments
+	commentPattern = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+	// packagePattern matches a package declaration, which may be annotated within package-info.java
+	packagePattern = regexp.MustCompile(`(?m)^\s*(?:@[\w.]+(?:\([^)]*\))?\s*)*package\s+([\w.]+)\s*;`)
+)
+
+// DiscoverSourceFiles iterates over each of the project's source roots in full looking for .java files. Returns an
+// error if a file's package declaration doesn't match its directory, outside of generated source roots.
 // TODO: make this a goroutine?
 func DiscoverSourceFiles(cfg project.ProjectConfig) ([]SourceFile, error) {
-	// get the source path
-	srcPath, err := GetSourcePath(cfg)
+	// get the source roots
+	roots, err := GetSourceRoots(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	// iterate recursively over child directories
+	// iterate recursively over child directories of each root
 	var files []SourceFile = []SourceFile{}
-	err = filepath.Walk(*srcPath, func(path string, info fs.FileInfo, err error) error {
+	mismatches := []string{}
+	for _, root := range roots {
+		exists, err := util.DoesPathExist(root.Path)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if !exists {
+			if root.Generated {
+				continue
+			}
+			return nil, fmt.Errorf("the source directory '%s' does not exist", root.Path)
 		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".java") {
-			text, err := os.ReadFile(path)
+
+		err = filepath.Walk(root.Path, func(path string, info fs.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
-			files = append(files, SourceFile{
-				Path:    path,
-				Content: string(text),
-			})
+			if !info.IsDir() && strings.HasSuffix(info.Name(), ".java") {
+				text, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				file := SourceFile{
+					Path:    path,
+					Content: string(text),
+					Root:    root,
+				}
+				if !root.Generated {
+					if mismatch := validatePackageDeclaration(file); mismatch != "" {
+						mismatches = append(mismatches, mismatch)
+					}
+				}
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
 	}
 
+	if len(mismatches) > 0 {
+		return nil, fmt.Errorf("package declarations do not match their directories:\n%s", strings.Join(mismatches, "\n"))
+	}
 	return files, nil
 }
+
+// GetPackageDeclaration gets the package a source file declares, empty for the default package
+func GetPackageDeclaration(content string) string {
+	match := packagePattern.FindStringSubmatch(commentPattern.ReplaceAllString(content, ""))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// validatePackageDeclaration returns a description of the mismatch if the file's package declaration doesn't match its
+// directory within its source root, or an empty string if it does
+func validatePackageDeclaration(file SourceFile) string {
+	if filepath.Base(file.Path) == "module-info.java" {
+		return ""
+	}
+	relativeDir, err := filepath.Rel(file.Root.Path, filepath.Dir(file.Path))
+	if err != nil {
+		return fmt.Sprintf("%s: %s", file.Path, err)
+	}
+	expected := ""
+	if relativeDir != "." {
+		expected = strings.ReplaceAll(filepath.ToSlash(relativeDir), "/", ".")
+	}
+	declared := GetPackageDeclaration(file.Content)
+	if declared == expected {
+		return ""
+	}
+	if declared == "" {
+		declared = "the default package"
+	} else {
+		declared = "'" + declared + "'"
+	}
+	if expected == "" {
+		expected = "the default package"
+	} else {
+		expected = "'" + expected + "'"
+	}
+	return fmt.Sprintf("  %s declares %s but is within %s", file.Path, declared, expected)
+}
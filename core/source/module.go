@@ -0,0 +1,44 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package source
+
+import (
+	"path/filepath"
+	"regexp"
+)
+
+var (
+	// moduleDeclarationPattern matches the declaration of a module within module-info.java
+	moduleDeclarationPattern = regexp.MustCompile(`(?m)^\s*(?:@[\w.]+(?:\([^)]*\))?\s*)*(?:open\s+)?module\s+([\w.]+)\s*\{`)
+	// requiresPattern matches a requires directive within module-info.java
+	requiresPattern = regexp.MustCompile(`\brequires\s+(?:(?:transitive|static)\s+)*([\w.]+)\s*;`)
+)
+
+// ModuleDeclaration is the module declared by a project's module-info.java
+type ModuleDeclaration struct {
+	Name     string
+	Requires []string
+	File     SourceFile
+}
+
+// FindModuleDeclaration finds the module-info.java among the given source files, returning nil if the project is not
+// modular
+func FindModuleDeclaration(files []SourceFile) *ModuleDeclaration {
+	for _, file := range files {
+		if filepath.Base(file.Path) != "module-info.java" {
+			continue
+		}
+		content := commentPattern.ReplaceAllString(file.Content, "")
+		declaration := &ModuleDeclaration{Requires: []string{}, File: file}
+		if match := moduleDeclarationPattern.FindStringSubmatch(content); match != nil {
+			declaration.Name = match[1]
+		}
+		for _, match := range requiresPattern.FindAllStringSubmatch(content, -1) {
+			declaration.Requires = append(declaration.Requires, match[1])
+		}
+		return declaration
+	}
+	return nil
+}
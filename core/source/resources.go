@@ -0,0 +1,133 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package source
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// ResourceFile represents a file within src/resources, RelativePath being its slash separated path within it
+type ResourceFile struct {
+	Path         string
+	RelativePath string
+}
+
+// placeholderPattern matches a ${...} placeholder
+var placeholderPattern = regexp.MustCompile(`\$\{([^${}]+)\}`)
+
+// GetResourcePath gets the path of the project's resources directory
+func GetResourcePath() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	path := wd
+
+	if util.IsDebugMode() {
+		path += "/ESPRESSO_DEBUG"
+	}
+
+	path += "/src/resources"
+	return path, nil
+}
+
+// DiscoverResourceFiles iterates over the project's resources directory, returning every file within it. Returns
+// nothing if the project has no resources directory.
+func DiscoverResourceFiles() ([]ResourceFile, error) {
+	resourcePath, err := GetResourcePath()
+	if err != nil {
+		return nil, err
+	}
+	files := []ResourceFile{}
+	exists, err := util.DoesPathExist(resourcePath)
+	if err != nil || !exists {
+		return files, err
+	}
+
+	err = filepath.WalkDir(resourcePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relativePath, err := filepath.Rel(resourcePath, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, ResourceFile{Path: path, RelativePath: filepath.ToSlash(relativePath)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// ShouldFilterResource returns if the resource matches any of the project's filter patterns
+func ShouldFilterResource(cfg project.ProjectConfig, file ResourceFile) bool {
+	for _, pattern := range cfg.Resources.Filter {
+		if matchGlob(strings.Split(pattern, "/"), strings.Split(file.RelativePath, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFilterValues gets the values substituted for placeholders within filtered resources: "project.name",
+// "project.version", "project.basePackage", "env.<NAME>" for each environment variable, then the project's properties,
+// which take precedence.
+func GetFilterValues(cfg project.ProjectConfig) map[string]string {
+	values := map[string]string{
+		"project.name":        cfg.Name,
+		"project.version":     project.GetVersionAsString(cfg.Version),
+		"project.basePackage": cfg.BasePackage,
+	}
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		values["env."+name] = value
+	}
+	for name, value := range cfg.Properties {
+		values[name] = value
+	}
+	return values
+}
+
+// FilterResource substitutes each ${...} placeholder within the content with its value. Placeholders without a value
+// are left untouched, as frameworks often resolve their own at runtime.
+func FilterResource(content string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		if value, ok := values[placeholder[2:len(placeholder)-1]]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// matchGlob matches path segments against pattern segments, where a "**" segment matches any number of segments and
+// every other segment follows path.Match
+func matchGlob(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlob(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, err := path.Match(pattern[0], segments[0])
+	return err == nil && matched && matchGlob(pattern[1:], segments[1:])
+}
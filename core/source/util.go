@@ -5,25 +5,39 @@
 package source
 
 import (
-	"os"
-	"strings"
+	"path/filepath"
 
 	"kerosenelabs.com/espresso/core/context/project"
-	"kerosenelabs.com/espresso/core/util"
 )
 
-// GetProjectPath gets the source path from the given ProjectConfig
-func GetSourcePath(cfg project.ProjectConfig) (*string, error) {
-	wd, err := os.Getwd()
+// SourceRoot is a directory holding package directories of .java files
+type SourceRoot struct {
+	Path string
+	// Generated is set for roots written by code generators, which may not exist and aren't validated
+	Generated bool
+}
+
+// GetSourceRoots gets the absolute path of each of the project's source roots, followed by its generated ones
+func GetSourceRoots(cfg project.ProjectConfig) ([]SourceRoot, error) {
+	projectPath, err := project.GetProjectPath()
 	if err != nil {
 		return nil, err
 	}
-	path := wd
 
-	if util.IsDebugMode() {
-		path += "/ESPRESSO_DEBUG"
+	roots := []SourceRoot{}
+	for _, dir := range cfg.GetSourceDirs() {
+		roots = append(roots, SourceRoot{Path: resolveProjectPath(projectPath, dir)})
+	}
+	for _, dir := range cfg.GeneratedSourceDirs {
+		roots = append(roots, SourceRoot{Path: resolveProjectPath(projectPath, dir), Generated: true})
 	}
+	return roots, nil
+}
 
-	path += "/src/java/" + strings.ReplaceAll(cfg.BasePackage, ".", "/")
-	return &path, nil
+// resolveProjectPath resolves a path relative to the project directory, leaving absolute paths untouched
+func resolveProjectPath(projectPath string, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(projectPath, path)
 }
@@ -0,0 +1,353 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package toolchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kerosenelabs.com/espresso/core/context/project"
+)
+
+const (
+	classFileMagic       = 0xCAFEBABE
+	accessFlagPublic     = 0x0001
+	accessFlagStatic     = 0x0008
+	mainMethodName       = "main"
+	mainMethodDescriptor = "([Ljava/lang/String;)V"
+)
+
+// ResolveMainClass gets the fully qualified main class of the project: its configured mainClass, or otherwise the only
+// compiled class with a "public static void main(String[])" method. Library projects have no main class, an empty
+// string is returned for them.
+func ResolveMainClass(cfg project.ProjectConfig) (string, error) {
+	if cfg.IsLibrary() {
+		return "", nil
+	}
+	buildPath, err := GetBuildPath(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	// ensure the configured main class was compiled
+	if cfg.MainClass != "" {
+		classPath := filepath.Join(*buildPath, strings.ReplaceAll(cfg.MainClass, ".", "/")+".class")
+		if _, err := os.Stat(classPath); err != nil {
+			return "", fmt.Errorf("the configured main class '%s' was not compiled (expected '%s')", cfg.MainClass, classPath)
+		}
+		return cfg.MainClass, nil
+	}
+
+	// otherwise find it
+	mainClasses, err := FindMainClasses(*buildPath)
+	if err != nil {
+		return "", err
+	}
+	switch len(mainClasses) {
+	case 0:
+		return "", errors.New("no class with a 'public static void main(String[])' method was found, set 'mainClass' or 'type: library' within espresso.yml")
+	case 1:
+		return mainClasses[0], nil
+	default:
+		return "", fmt.Errorf("several classes have a 'public static void main(String[])' method (%s), set 'mainClass' within espresso.yml to choose one", strings.Join(mainClasses, ", "))
+	}
+}
+
+// FindMainClasses scans every class file beneath the given directory, returning the fully qualified name of each one
+// declaring a "public static void main(String[])" method, sorted.
+func FindMainClasses(classesPath string) ([]string, error) {
+	mainClasses := []string{}
+	err := filepath.WalkDir(classesPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, ".class") || entry.Name() == "module-info.class" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		className, hasMain, err := readClassFile(content)
+		if err != nil {
+			return fmt.Errorf("unable to read class file '%s': %w", path, err)
+		}
+		if hasMain {
+			mainClasses = append(mainClasses, className)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(mainClasses)
+	return mainClasses, nil
+}
+
+// classFile is the parts of a class file we care about. See chapter 4 of the JVM specification for the format.
+type classFile struct {
+	content   []byte
+	poolCount uint16
+	// poolEnd is the offset just past the constant pool
+	poolEnd int
+	utf8s   map[uint16]string
+	classes map[uint16]uint16
+	modules map[uint16]uint16
+	name    string
+	hasMain bool
+	// attributesOffset is the offset of the class's attributes_count, which are last within the file
+	attributesOffset int
+	attributes       []classAttribute
+}
+
+// classAttribute is an attribute of the class itself
+type classAttribute struct {
+	name string
+	// offset is the offset of the attribute's value
+	offset int
+	length uint32
+}
+
+// readClassFile reads the fully qualified name of a class file's class and whether it declares a
+// "public static void main(String[])" method
+func readClassFile(content []byte) (string, bool, error) {
+	class, err := parseClassFile(content)
+	if err != nil {
+		return "", false, err
+	}
+	return class.name, class.hasMain, nil
+}
+
+// parseClassFile parses the given class file
+func parseClassFile(content []byte) (*classFile, error) {
+	reader := bytes.NewReader(content)
+	offset := func() int {
+		return int(reader.Size()) - reader.Len()
+	}
+	var header struct {
+		Magic        uint32
+		MinorVersion uint16
+		MajorVersion uint16
+		PoolCount    uint16
+	}
+	err := binary.Read(reader, binary.BigEndian, &header)
+	if err != nil {
+		return nil, err
+	}
+	if header.Magic != classFileMagic {
+		return nil, errors.New("not a class file")
+	}
+	class := &classFile{
+		content:   content,
+		poolCount: header.PoolCount,
+		utf8s:     map[uint16]string{},
+		classes:   map[uint16]uint16{},
+		modules:   map[uint16]uint16{},
+	}
+
+	// read the constant pool, we only care about utf8 strings and class and module references
+	for i := uint16(1); i < header.PoolCount; i++ {
+		tag, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case 1: // utf8
+			length, err := readUint16(reader)
+			if err != nil {
+				return nil, err
+			}
+			value := make([]byte, length)
+			_, err = io.ReadFull(reader, value)
+			if err != nil {
+				return nil, err
+			}
+			class.utf8s[i] = string(value)
+		case 7, 19: // class, module
+			nameIndex, err := readUint16(reader)
+			if err != nil {
+				return nil, err
+			}
+			if tag == 7 {
+				class.classes[i] = nameIndex
+			} else {
+				class.modules[i] = nameIndex
+			}
+		case 8, 16, 20: // string, method type, package
+			_, err = reader.Seek(2, io.SeekCurrent)
+		case 15: // method handle
+			_, err = reader.Seek(3, io.SeekCurrent)
+		case 3, 4, 9, 10, 11, 12, 17, 18: // integer, float, refs, name and type, dynamic, invoke dynamic
+			_, err = reader.Seek(4, io.SeekCurrent)
+		case 5, 6: // long and double take up two entries
+			_, err = reader.Seek(8, io.SeekCurrent)
+			i++
+		default:
+			return nil, fmt.Errorf("unknown constant pool tag %d", tag)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	class.poolEnd = offset()
+
+	// read the class
+	var info struct {
+		AccessFlags     uint16
+		ThisClass       uint16
+		SuperClass      uint16
+		InterfacesCount uint16
+	}
+	err = binary.Read(reader, binary.BigEndian, &info)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := class.utf8s[class.classes[info.ThisClass]]
+	if !ok {
+		return nil, errors.New("invalid class name")
+	}
+	class.name = strings.ReplaceAll(name, "/", ".")
+	_, err = reader.Seek(int64(info.InterfacesCount)*2, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	// skip the fields, then look through the methods
+	for _, isMethods := range []bool{false, true} {
+		count, err := readUint16(reader)
+		if err != nil {
+			return nil, err
+		}
+		for j := uint16(0); j < count; j++ {
+			var member struct {
+				AccessFlags     uint16
+				NameIndex       uint16
+				DescriptorIndex uint16
+				AttributesCount uint16
+			}
+			err = binary.Read(reader, binary.BigEndian, &member)
+			if err != nil {
+				return nil, err
+			}
+			if isMethods && member.AccessFlags&(accessFlagPublic|accessFlagStatic) == accessFlagPublic|accessFlagStatic &&
+				class.utf8s[member.NameIndex] == mainMethodName && class.utf8s[member.DescriptorIndex] == mainMethodDescriptor {
+				class.hasMain = true
+			}
+			_, err = readClassAttributes(reader, class.utf8s, member.AttributesCount, offset)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// read the class's attributes
+	class.attributesOffset = offset()
+	count, err := readUint16(reader)
+	if err != nil {
+		return nil, err
+	}
+	class.attributes, err = readClassAttributes(reader, class.utf8s, count, offset)
+	if err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// readClassAttributes reads the given number of attributes, skipping over their values
+func readClassAttributes(reader *bytes.Reader, utf8s map[uint16]string, count uint16, offset func() int) ([]classAttribute, error) {
+	attributes := []classAttribute{}
+	for k := uint16(0); k < count; k++ {
+		var attribute struct {
+			NameIndex uint16
+			Length    uint32
+		}
+		err := binary.Read(reader, binary.BigEndian, &attribute)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, classAttribute{name: utf8s[attribute.NameIndex], offset: offset(), length: attribute.Length})
+		if int64(attribute.Length) > int64(reader.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		_, err = reader.Seek(int64(attribute.Length), io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return attributes, nil
+}
+
+// getModuleName gets the name of the module declared by a module-info.class
+func (class *classFile) getModuleName() (string, error) {
+	for _, attribute := range class.attributes {
+		if attribute.name != "Module" || attribute.length < 2 {
+			continue
+		}
+		index := binary.BigEndian.Uint16(class.content[attribute.offset:])
+		if name, ok := class.utf8s[class.modules[index]]; ok {
+			return name, nil
+		}
+	}
+	return "", errors.New("no module is declared")
+}
+
+// setModuleMainClass returns a copy of a module-info.class with its ModuleMainClass attribute set to the given class,
+// as "jar --main-class" does
+func (class *classFile) setModuleMainClass(mainClass string) []byte {
+	// add the attribute's name, the class's name and the class to the end of the constant pool
+	attributeNameIndex := class.poolCount
+	classIndex := class.poolCount + 2
+	pool := appendUtf8Constant(nil, "ModuleMainClass")
+	pool = appendUtf8Constant(pool, strings.ReplaceAll(mainClass, ".", "/"))
+	pool = append(pool, 7)
+	pool = binary.BigEndian.AppendUint16(pool, class.poolCount+1)
+
+	// point an existing ModuleMainClass attribute at the class, or add one
+	attributesCount := uint16(len(class.attributes))
+	attributes := bytes.Clone(class.content[class.attributesOffset+2:])
+	found := false
+	for _, attribute := range class.attributes {
+		if attribute.name == "ModuleMainClass" && attribute.length == 2 {
+			binary.BigEndian.PutUint16(attributes[attribute.offset-class.attributesOffset-2:], classIndex)
+			found = true
+		}
+	}
+	if !found {
+		attributesCount++
+		attributes = binary.BigEndian.AppendUint16(attributes, attributeNameIndex)
+		attributes = binary.BigEndian.AppendUint32(attributes, 2)
+		attributes = binary.BigEndian.AppendUint16(attributes, classIndex)
+	}
+
+	out := bytes.Clone(class.content[:8])
+	out = binary.BigEndian.AppendUint16(out, class.poolCount+3)
+	out = append(out, class.content[10:class.poolEnd]...)
+	out = append(out, pool...)
+	out = append(out, class.content[class.poolEnd:class.attributesOffset]...)
+	out = binary.BigEndian.AppendUint16(out, attributesCount)
+	return append(out, attributes...)
+}
+
+// appendUtf8Constant appends a utf8 constant pool entry
+func appendUtf8Constant(pool []byte, value string) []byte {
+	pool = append(pool, 1)
+	pool = binary.BigEndian.AppendUint16(pool, uint16(len(value)))
+	return append(pool, value...)
+}
+
+// readUint16 reads a big endian uint16
+func readUint16(reader io.Reader) (uint16, error) {
+	var value uint16
+	err := binary.Read(reader, binary.BigEndian, &value)
+	return value, err
+}
@@ -7,6 +7,7 @@ package toolchain
 import (
 	"errors"
 	"os/exec"
+	"strings"
 
 	"kerosenelabs.com/espresso/core/context/project"
 	"kerosenelabs.com/espresso/core/dependency"
@@ -14,36 +15,51 @@ import (
 	"kerosenelabs.com/espresso/core/util"
 )
 
-// CompileSourceFile compiles the sourcefile with the given project toolchain
-func CompileSourceFile(cfg project.ProjectConfig, srcFile source.SourceFile) error {
-	// initialize our classpath value
-	cpVal := ""
-	if util.IsDebugMode() {
-		cpVal = "ESPRESSO_DEBUG/src/java"
-	} else {
-		cpVal = "src/java"
+// GetClassPath gets the classpath the project's sources are compiled against: its sources and every dependency
+func GetClassPath(cfg project.ProjectConfig) (string, error) {
+	// initialize our classpath value with the source roots
+	roots, err := source.GetSourceRoots(cfg)
+	if err != nil {
+		return "", err
 	}
+	cpVal := strings.Join(getSourceRootPaths(roots), ":")
 
-	// iterate over dependencies, resolve each one and add it to the classpath argument value
-	for _, dep := range cfg.Dependencies {
-		// resolve our dependency
-		resolvedDependency, err := dependency.ResolveDependency(dep, cfg.Registries)
-		if err != nil {
-			return err
-		}
-
+	// resolve our dependencies, add each one to the classpath argument value
+	resolvedDependencies, err := dependency.ResolveProjectDependencies(cfg)
+	if err != nil {
+		return "", err
+	}
+	for _, resolvedDependency := range resolvedDependencies {
 		// get our cache path for the jar
 		depCachePath, err := resolvedDependency.GetCachePath()
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		// append it to the classpath value
 		cpVal += ":" + depCachePath.Absolute
 	}
+	return cpVal, nil
+}
 
+// getSourceRootPaths gets the path of each source root
+func getSourceRootPaths(roots []source.SourceRoot) []string {
+	paths := []string{}
+	for _, root := range roots {
+		paths = append(paths, root.Path)
+	}
+	return paths
+}
+
+// CompileSourceFile compiles the sourcefile with the given project toolchain against the given classpath, see
+// GetClassPath. The classpath is resolved once by the caller as every source file shares it.
+func CompileSourceFile(cfg project.ProjectConfig, cpVal string, srcFile source.SourceFile) error {
 	// run the compiler
-	command := cfg.Toolchain.Path + "/bin/javac"
+	javaHome, err := ResolveToolchainPath(cfg)
+	if err != nil {
+		return err
+	}
+	command := javaHome + "/bin/javac"
 	args := []string{}
 	if util.IsDebugMode() {
 		args = append(args, "-cp", cpVal, "-d", "ESPRESSO_DEBUG/build")
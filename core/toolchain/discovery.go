@@ -0,0 +1,334 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package toolchain
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// SourceAdded is the Source of a JDK added by hand rather than discovered
+const SourceAdded = "added"
+
+// vendorAliases maps the names vendors are commonly known by to their release file's IMPLEMENTOR
+var vendorAliases = map[string]string{
+	"temurin":    "eclipse adoptium",
+	"adoptium":   "eclipse adoptium",
+	"corretto":   "amazon.com inc.",
+	"zulu":       "azul systems, inc.",
+	"azul":       "azul systems, inc.",
+	"liberica":   "bellsoft",
+	"microsoft":  "microsoft",
+	"oracle":     "oracle corporation",
+	"graalvm":    "graalvm",
+	"sapmachine": "sap se",
+}
+
+// JavaInstallation is a JDK on this machine, described by its release file
+type JavaInstallation struct {
+	Path string `yaml:"path" json:"path"`
+	// Version is the release's JAVA_VERSION (ex: "21.0.2")
+	Version string `yaml:"version" json:"version"`
+	// Vendor is the release's IMPLEMENTOR (ex: "Eclipse Adoptium")
+	Vendor string `yaml:"vendor,omitempty" json:"vendor,omitempty"`
+	// VendorVersion is the release's IMPLEMENTOR_VERSION (ex: "Temurin-21.0.2+13")
+	VendorVersion string `yaml:"vendorVersion,omitempty" json:"vendorVersion,omitempty"`
+	// Source is where the JDK was found (ex: "sdkman"), or "added"
+	Source string `yaml:"source" json:"source"`
+}
+
+// GetFeatureVersion gets the feature version of the JDK (ex: 21)
+func (installation JavaInstallation) GetFeatureVersion() int {
+	return GetJavaFeatureVersion(map[string]string{"JAVA_VERSION": installation.Version})
+}
+
+// MatchesVendor returns if the JDK is from the given vendor, by name or a common alias (ex: "temurin")
+func (installation JavaInstallation) MatchesVendor(vendor string) bool {
+	vendor = strings.ToLower(vendor)
+	implementor := strings.ToLower(installation.Vendor)
+	if alias, ok := vendorAliases[vendor]; ok && strings.Contains(implementor, alias) {
+		return true
+	}
+	return strings.Contains(implementor, vendor) || strings.Contains(strings.ToLower(installation.VendorVersion), vendor)
+}
+
+// storedToolchains is the file within the Espresso home listing this machine's JDKs
+type storedToolchains struct {
+	Toolchains []JavaInstallation `yaml:"toolchains"`
+}
+
+// resolvedToolchains memoizes ResolveToolchainPath, as every compiler invocation needs it
+var resolvedToolchains = map[project.Toolchain]string{}
+var resolvedToolchainsMutex sync.Mutex
+
+// ReadJavaInstallation reads the JDK at the given path, returning an error if it has no release file or no compiler
+func ReadJavaInstallation(path string, source string) (*JavaInstallation, error) {
+	release, err := ReadJavaRelease(path)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' has no readable release file: %w", path, err)
+	}
+	if release["JAVA_VERSION"] == "" {
+		return nil, fmt.Errorf("'%s' has no JAVA_VERSION within its release file", path)
+	}
+	compiler := "javac"
+	if runtime.GOOS == "windows" {
+		compiler = "javac.exe"
+	}
+	exists, err := util.DoesPathExist(filepath.Join(path, "bin", compiler))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("'%s' is not a JDK, it has no compiler", path)
+	}
+	return &JavaInstallation{
+		Path:          path,
+		Version:       release["JAVA_VERSION"],
+		Vendor:        release["IMPLEMENTOR"],
+		VendorVersion: release["IMPLEMENTOR_VERSION"],
+		Source:        source,
+	}, nil
+}
+
+// DiscoverJavaInstallations finds the JDKs within JAVA_HOME, /usr/lib/jvm, macOS's JavaVirtualMachines, SDKMAN and asdf
+func DiscoverJavaInstallations() []JavaInstallation {
+	candidates := [][2]string{}
+	if javaHome, err := util.GetJavaHome(); err == nil && *javaHome != "" {
+		candidates = append(candidates, [2]string{*javaHome, "JAVA_HOME"})
+	}
+	addMatches := func(pattern string, source string) {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			candidates = append(candidates, [2]string{match, source})
+		}
+	}
+	addMatches("/usr/lib/jvm/*", "/usr/lib/jvm")
+	addMatches("/Library/Java/JavaVirtualMachines/*/Contents/Home", "/Library/Java/JavaVirtualMachines")
+	homeDir, _ := os.UserHomeDir()
+	sdkmanDir, present := os.LookupEnv("SDKMAN_DIR")
+	if !present && homeDir != "" {
+		sdkmanDir = filepath.Join(homeDir, ".sdkman")
+	}
+	if sdkmanDir != "" {
+		addMatches(filepath.Join(sdkmanDir, "candidates", "java", "*"), "sdkman")
+	}
+	asdfDir, present := os.LookupEnv("ASDF_DATA_DIR")
+	if !present && homeDir != "" {
+		asdfDir = filepath.Join(homeDir, ".asdf")
+	}
+	if asdfDir != "" {
+		addMatches(filepath.Join(asdfDir, "installs", "java", "*"), "asdf")
+	}
+
+	// the same JDK is often linked from several places (ex: /usr/lib/jvm/default-java, SDKMAN's current)
+	seen := map[string]bool{}
+	installations := []JavaInstallation{}
+	for _, candidate := range candidates {
+		path, err := filepath.EvalSymlinks(candidate[0])
+		if err != nil || seen[path] {
+			continue
+		}
+		seen[path] = true
+		installation, err := ReadJavaInstallation(path, candidate[1])
+		if err == nil {
+			installations = append(installations, *installation)
+		}
+	}
+	return installations
+}
+
+// GetToolchainsPath gets the path to the file listing this machine's JDKs, within the Espresso home
+func GetToolchainsPath() (string, error) {
+	espressoPath, err := util.GetEspressoDirectoryPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(espressoPath, "toolchains.yml"), nil
+}
+
+// GetJavaInstallations gets this machine's JDKs as last discovered, or discovers them if they never were
+func GetJavaInstallations() ([]JavaInstallation, error) {
+	toolchainsPath, err := GetToolchainsPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(toolchainsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return RefreshJavaInstallations()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored storedToolchains
+	err = yaml.Unmarshal(content, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse '%s': %w", toolchainsPath, err)
+	}
+	return stored.Toolchains, nil
+}
+
+// RefreshJavaInstallations discovers this machine's JDKs, keeping those added by hand that still exist, and stores
+// them within the Espresso home
+func RefreshJavaInstallations() ([]JavaInstallation, error) {
+	installations := DiscoverJavaInstallations()
+	toolchainsPath, err := GetToolchainsPath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(toolchainsPath)
+	if err == nil {
+		var stored storedToolchains
+		if yaml.Unmarshal(content, &stored) == nil {
+			for _, previous := range stored.Toolchains {
+				if previous.Source != SourceAdded {
+					continue
+				}
+				installation, err := ReadJavaInstallation(previous.Path, SourceAdded)
+				if err == nil {
+					installations = appendJavaInstallation(installations, *installation)
+				}
+			}
+		}
+	}
+	return installations, saveJavaInstallations(installations)
+}
+
+// AddJavaInstallation adds the JDK at the given path to this machine's JDKs, for those installed where they aren't
+// discovered
+func AddJavaInstallation(path string) (*JavaInstallation, error) {
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	absolutePath, err = filepath.EvalSymlinks(absolutePath)
+	if err != nil {
+		return nil, err
+	}
+	installation, err := ReadJavaInstallation(absolutePath, SourceAdded)
+	if err != nil {
+		return nil, err
+	}
+	installations, err := GetJavaInstallations()
+	if err != nil {
+		return nil, err
+	}
+	return installation, saveJavaInstallations(appendJavaInstallation(installations, *installation))
+}
+
+// FindJavaInstallation finds the newest JDK satisfying the requirement, returning nil if there's none
+func FindJavaInstallation(installations []JavaInstallation, requirement project.Toolchain) *JavaInstallation {
+	var found *JavaInstallation
+	for i, installation := range installations {
+		if installation.GetFeatureVersion() != requirement.Version {
+			continue
+		}
+		if requirement.Vendor != "" && !installation.MatchesVendor(requirement.Vendor) {
+			continue
+		}
+		if found == nil || compareJavaVersions(installation.Version, found.Version) > 0 {
+			found = &installations[i]
+		}
+	}
+	return found
+}
+
+// ResolveToolchainPath gets the path to the JDK the project requires on this machine. Projects without a required
+// version use their legacy toolchain path, otherwise JAVA_HOME.
+func ResolveToolchainPath(cfg project.ProjectConfig) (string, error) {
+	resolvedToolchainsMutex.Lock()
+	defer resolvedToolchainsMutex.Unlock()
+	if path, ok := resolvedToolchains[cfg.Toolchain]; ok {
+		return path, nil
+	}
+
+	path, err := resolveToolchainPath(cfg.Toolchain)
+	if err != nil {
+		return "", err
+	}
+	resolvedToolchains[cfg.Toolchain] = path
+	return path, nil
+}
+
+// resolveToolchainPath resolves the requirement against the stored JDKs, discovering them again if none of them
+// (still) satisfy it
+func resolveToolchainPath(requirement project.Toolchain) (string, error) {
+	if requirement.Version == 0 {
+		if requirement.Path != "" {
+			return requirement.Path, nil
+		}
+		javaHome, err := util.GetJavaHome()
+		if err != nil {
+			return "", errors.New("the project requires no toolchain version and JAVA_HOME is not set")
+		}
+		return *javaHome, nil
+	}
+
+	installations, err := GetJavaInstallations()
+	if err != nil {
+		return "", err
+	}
+	found := FindJavaInstallation(installations, requirement)
+	if found != nil {
+		if _, err := ReadJavaInstallation(found.Path, found.Source); err != nil {
+			found = nil
+		}
+	}
+	if found == nil {
+		installations, err = RefreshJavaInstallations()
+		if err != nil {
+			return "", err
+		}
+		found = FindJavaInstallation(installations, requirement)
+	}
+	if found == nil {
+		description := strconv.Itoa(requirement.Version)
+		if requirement.Vendor != "" {
+			description += " (" + requirement.Vendor + ")"
+		}
+		return "", fmt.Errorf("no JDK %s was found on this machine, install one or add it with 'espresso toolchain add <path>'", description)
+	}
+	return found.Path, nil
+}
+
+// saveJavaInstallations stores the JDKs within the Espresso home, newest first
+func saveJavaInstallations(installations []JavaInstallation) error {
+	sort.SliceStable(installations, func(i, j int) bool {
+		return compareJavaVersions(installations[i].Version, installations[j].Version) > 0
+	})
+	toolchainsPath, err := GetToolchainsPath()
+	if err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(storedToolchains{Toolchains: installations})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(toolchainsPath), 0755)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(toolchainsPath, content, 0644)
+}
+
+// appendJavaInstallation appends the JDK unless one at the same path is already listed
+func appendJavaInstallation(installations []JavaInstallation, installation JavaInstallation) []JavaInstallation {
+	for _, existing := range installations {
+		if existing.Path == installation.Path {
+			return installations
+		}
+	}
+	return append(installations, installation)
+}
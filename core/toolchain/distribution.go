@@ -0,0 +1,316 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package toolchain
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// DefaultConfDir is the directory copied into a distribution's conf directory when none is configured
+const DefaultConfDir = "src/conf"
+
+// shellLauncherTemplate builds the classpath relative to the distribution and runs the main class
+const shellLauncherTemplate = `#!/bin/sh
+# Launches %[1]s, honoring JAVA_HOME and JAVA_OPTS
+
+# resolve symlinks so the distribution can be found from wherever the script is linked
+PRG="$0"
+while [ -h "$PRG" ]; do
+    LINK=$(ls -ld "$PRG" | sed 's/.*-> //')
+    case "$LINK" in
+        /*) PRG="$LINK" ;;
+        *) PRG="$(dirname "$PRG")/$LINK" ;;
+    esac
+done
+APP_HOME=$(cd "$(dirname "$PRG")/.." && pwd -P)
+
+if [ -n "$JAVA_HOME" ]; then
+    JAVACMD="$JAVA_HOME/bin/java"
+else
+    JAVACMD=java
+fi
+
+CLASSPATH=%[2]s
+exec "$JAVACMD" $JAVA_OPTS -cp "$CLASSPATH" %[3]s "$@"
+`
+
+// batchLauncherTemplate is the Windows equivalent of shellLauncherTemplate
+const batchLauncherTemplate = `@echo off
+rem Launches %[1]s, honoring JAVA_HOME and JAVA_OPTS
+setlocal
+set "APP_HOME=%%~dp0.."
+
+set JAVACMD=java.exe
+if not defined JAVA_HOME goto run
+set "JAVACMD=%%JAVA_HOME%%\bin\java.exe"
+
+:run
+set "CLASSPATH=%[2]s"
+"%%JAVACMD%%" %%JAVA_OPTS%% -cp "%%CLASSPATH%%" %[3]s %%*
+endlocal & exit /b %%ERRORLEVEL%%
+`
+
+// distributionEntry is a file or directory within a distribution archive. Files are either generated content or
+// copied from a path on disk, streamed when the archive is written rather than held in memory.
+type distributionEntry struct {
+	name    string
+	content []byte
+	path    string
+	mode    fs.FileMode
+}
+
+// open opens the entry's content, returning its size along with it
+func (entry distributionEntry) open() (io.ReadCloser, int64, error) {
+	if entry.path == "" {
+		return io.NopCloser(bytes.NewReader(entry.content)), int64(len(entry.content)), nil
+	}
+	file, err := os.Open(entry.path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// GetDistributionName gets the name of the project's distribution archives, and the directory within them
+func GetDistributionName(cfg project.ProjectConfig) string {
+	return cfg.Name + "-" + project.GetVersionAsString(cfg.Version)
+}
+
+// GetConfPath gets the absolute path to the directory copied into the distribution's conf directory, returning an
+// empty string if the default one doesn't exist
+func GetConfPath(cfg project.ProjectConfig) (string, error) {
+	projectPath, err := project.GetProjectPath()
+	if err != nil {
+		return "", err
+	}
+	confDir := cfg.Distribution.ConfDir
+	if confDir == "" {
+		confDir = DefaultConfDir
+	}
+	if !filepath.IsAbs(confDir) {
+		confDir = filepath.Join(projectPath, confDir)
+	}
+	info, err := os.Stat(confDir)
+	if err != nil {
+		if os.IsNotExist(err) && cfg.Distribution.ConfDir == "" {
+			return "", nil
+		}
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("'%s' is not a directory", confDir)
+	}
+	return confDir, nil
+}
+
+// PackageDistribution creates the "<name>-<version>.tar.gz" and ".zip" distributions of the built application, each
+// holding its jars in lib, launchers in bin and configuration in conf. Returns the paths of the archives.
+func PackageDistribution(cfg project.ProjectConfig) ([]string, error) {
+	mainClass, err := ResolveMainClass(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if mainClass == "" {
+		return nil, fmt.Errorf("no main class was found, set mainClass within the project's config")
+	}
+	jars, err := GetDistributedJars(cfg)
+	if err != nil {
+		return nil, err
+	}
+	name := GetDistributionName(cfg)
+	entries := []distributionEntry{{name: name + "/", mode: fs.ModeDir | 0755}}
+
+	// the application's jar is named after the project rather than "dist"
+	entries = append(entries, distributionEntry{name: name + "/lib/", mode: fs.ModeDir | 0755})
+	shellClassPath, batchClassPath := []string{}, []string{}
+	for i, jar := range jars {
+		jarName := filepath.Base(jar)
+		if i == 0 {
+			jarName = name + ".jar"
+		}
+		entries = append(entries, distributionEntry{name: name + "/lib/" + jarName, path: jar, mode: 0644})
+		shellClassPath = append(shellClassPath, "$APP_HOME/lib/"+jarName)
+		batchClassPath = append(batchClassPath, `%APP_HOME%\lib\`+jarName)
+	}
+
+	// launchers
+	shellLauncher := fmt.Sprintf(shellLauncherTemplate, cfg.Name, `"`+strings.Join(shellClassPath, ":")+`"`, mainClass)
+	batchLauncher := fmt.Sprintf(batchLauncherTemplate, cfg.Name, strings.Join(batchClassPath, ";"), mainClass)
+	entries = append(entries,
+		distributionEntry{name: name + "/bin/", mode: fs.ModeDir | 0755},
+		distributionEntry{name: name + "/bin/" + cfg.Name, content: []byte(shellLauncher), mode: 0755},
+		distributionEntry{name: name + "/bin/" + cfg.Name + ".bat", content: []byte(strings.ReplaceAll(batchLauncher, "\n", "\r\n")), mode: 0644},
+	)
+
+	// configuration
+	confPath, err := GetConfPath(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if confPath != "" {
+		confEntries, err := getDistributionDirectoryEntries(confPath, name+"/conf/")
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, confEntries...)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	// write the archives
+	timestamp, err := GetJarTimestamp()
+	if err != nil {
+		return nil, err
+	}
+	distPath, err := GetDistPath(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tarPath := filepath.Join(*distPath, name+".tar.gz")
+	err = writeTarGzArchive(tarPath, entries, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	zipPath := filepath.Join(*distPath, name+".zip")
+	err = writeZipArchive(zipPath, entries, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return []string{tarPath, zipPath}, nil
+}
+
+// getDistributionDirectoryEntries gets an entry for everything within the directory, prefixed with the given name
+func getDistributionDirectoryEntries(directory string, prefix string) ([]distributionEntry, error) {
+	entries := []distributionEntry{}
+	err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		if relativePath == "." {
+			entries = append(entries, distributionEntry{name: prefix, mode: fs.ModeDir | 0755})
+			return nil
+		}
+		name := prefix + filepath.ToSlash(relativePath)
+		if entry.IsDir() {
+			entries = append(entries, distributionEntry{name: name + "/", mode: fs.ModeDir | 0755})
+			return nil
+		}
+		entries = append(entries, distributionEntry{name: name, path: path, mode: 0644})
+		return nil
+	})
+	return entries, err
+}
+
+// writeTarGzArchive writes the entries into a gzipped tar, with fixed timestamps and owners so it's reproducible.
+// The archive is replaced atomically, so a failed write leaves the previous one in place.
+func writeTarGzArchive(archivePath string, entries []distributionEntry, timestamp time.Time) error {
+	return util.WriteAtomic(archivePath, 0644, func(file io.Writer) error {
+		gzipWriter := gzip.NewWriter(file)
+		tarWriter := tar.NewWriter(gzipWriter)
+		for _, entry := range entries {
+			var err error
+			if entry.mode.IsDir() {
+				err = tarWriter.WriteHeader(&tar.Header{
+					Typeflag: tar.TypeDir,
+					Name:     entry.name,
+					Mode:     int64(entry.mode.Perm()),
+					ModTime:  timestamp,
+				})
+			} else {
+				err = writeTarFile(tarWriter, entry, timestamp)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		err := tarWriter.Close()
+		if err != nil {
+			return err
+		}
+		return gzipWriter.Close()
+	})
+}
+
+// writeTarFile writes a file entry, streaming its content
+func writeTarFile(writer *tar.Writer, entry distributionEntry, timestamp time.Time) error {
+	content, size, err := entry.open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	err = writer.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     entry.name,
+		Mode:     int64(entry.mode.Perm()),
+		ModTime:  timestamp,
+		Size:     size,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, content)
+	return err
+}
+
+// writeZipArchive writes the entries into a zip, with fixed timestamps so it's reproducible. The archive is replaced
+// atomically, so a failed write leaves the previous one in place.
+func writeZipArchive(archivePath string, entries []distributionEntry, timestamp time.Time) error {
+	return util.WriteAtomic(archivePath, 0644, func(file io.Writer) error {
+		writer := zip.NewWriter(file)
+		for _, entry := range entries {
+			var err error
+			if entry.mode.IsDir() {
+				err = writeJarDirectory(writer, entry.name, timestamp)
+			} else {
+				err = writeZipFile(writer, entry, timestamp)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return writer.Close()
+	})
+}
+
+// writeZipFile writes a file entry keeping its mode, so launchers stay executable when unzipped
+func writeZipFile(writer *zip.Writer, entry distributionEntry, timestamp time.Time) error {
+	content, _, err := entry.open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: timestamp}
+	header.SetMode(entry.mode)
+	entryWriter, err := writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entryWriter, content)
+	return err
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package toolchain
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultJarTimestamp is the modification time of every jar entry when SOURCE_DATE_EPOCH isn't set. It's a month past
+// the zip epoch so no timezone can shift it before 1980.
+var defaultJarTimestamp = time.Date(1980, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+// jarEntry is a file or directory to be written into a jar
+type jarEntry struct {
+	name string
+	path string
+}
+
+// GetJarTimestamp gets the modification time given to every jar entry, SOURCE_DATE_EPOCH if it's set
+func GetJarTimestamp() (time.Time, error) {
+	epoch, present := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !present || epoch == "" {
+		return defaultJarTimestamp, nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("SOURCE_DATE_EPOCH '%s' is not a number of seconds: %w", epoch, err)
+	}
+	timestamp := time.Unix(seconds, 0).UTC()
+	if timestamp.Before(defaultJarTimestamp) {
+		return defaultJarTimestamp, nil
+	}
+	return timestamp, nil
+}
+
+// WriteJar writes a reproducible jar at the given path containing the given manifest and the contents of each given
+// directory. The same inputs always produce the same bytes: entries are sorted (with META-INF/ and its MANIFEST.MF
+// first, as java.util.jar.JarInputStream expects), timestamps are fixed and permissions are normalized. When
+// directories contain the same entry, the first one wins. Any META-INF/MANIFEST.MF within the directories is replaced
+// by the given manifest.
+func WriteJar(jarPath string, manifest string, directories []string) error {
+	timestamp, err := GetJarTimestamp()
+	if err != nil {
+		return err
+	}
+
+	// collect every entry
+	entries := map[string]jarEntry{}
+	for _, directory := range directories {
+		err = filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			relativePath, err := filepath.Rel(directory, path)
+			if err != nil || relativePath == "." {
+				return err
+			}
+			name := filepath.ToSlash(relativePath)
+			if entry.IsDir() {
+				name += "/"
+			}
+			if _, ok := entries[name]; !ok {
+				entries[name] = jarEntry{name: name, path: path}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	delete(entries, "META-INF/")
+	delete(entries, "META-INF/MANIFEST.MF")
+	names := []string{}
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		iMeta, jMeta := strings.HasPrefix(names[i], "META-INF/"), strings.HasPrefix(names[j], "META-INF/")
+		if iMeta != jMeta {
+			return iMeta
+		}
+		return names[i] < names[j]
+	})
+
+	// write the jar
+	file, err := os.Create(jarPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := zip.NewWriter(file)
+	err = writeJarDirectory(writer, "META-INF/", timestamp)
+	if err != nil {
+		return err
+	}
+	err = writeJarFile(writer, "META-INF/MANIFEST.MF", strings.NewReader(manifest), timestamp)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		entry := entries[name]
+		if strings.HasSuffix(name, "/") {
+			err = writeJarDirectory(writer, name, timestamp)
+		} else {
+			err = writeJarFileFromPath(writer, entry, timestamp)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// writeJarDirectory writes a directory entry
+func writeJarDirectory(writer *zip.Writer, name string, timestamp time.Time) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Store, Modified: timestamp}
+	header.SetMode(fs.ModeDir | 0755)
+	_, err := writer.CreateHeader(header)
+	return err
+}
+
+// writeJarFileFromPath writes a file entry with the contents of the file on disk
+func writeJarFileFromPath(writer *zip.Writer, entry jarEntry, timestamp time.Time) error {
+	file, err := os.Open(entry.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeJarFile(writer, entry.name, file, timestamp)
+}
+
+// writeJarFile writes a file entry with the given contents
+func writeJarFile(writer *zip.Writer, name string, content io.Reader, timestamp time.Time) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: timestamp}
+	header.SetMode(0644)
+	entryWriter, err := writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entryWriter, content)
+	return err
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package toolchain
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// launcherTemplate runs the application's jar with the runtime image it sits within, from wherever it's invoked
+const launcherTemplate = `#!/bin/sh
+# Launches %s with the runtime image created by Espresso
+BIN_DIR=$(cd "$(dirname "$0")" && pwd)
+exec "$BIN_DIR/java" $JAVA_OPTS -jar "$BIN_DIR/../../%s" "$@"
+`
+
+// GetRuntimePath gets the absolute path to the runtime image within the dist directory
+func GetRuntimePath(cfg project.ProjectConfig) (string, error) {
+	distPath, err := GetDistPath(cfg)
+	if err != nil {
+		return "", err
+	}
+	return *distPath + "/runtime", nil
+}
+
+// GetDistributedJars gets the project's jar followed by every dependency jar within dist/libs, as of the last build
+// (which clears dist/libs before copying the dependencies into it)
+func GetDistributedJars(cfg project.ProjectConfig) ([]string, error) {
+	jarPath, err := GetJarPath(cfg)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := util.DoesPathExist(jarPath)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("'%s' doesn't exist, build the project first", jarPath)
+	}
+	libs, err := filepath.Glob(filepath.Join(filepath.Dir(jarPath), "libs", "*.jar"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(libs)
+	return append([]string{jarPath}, libs...), nil
+}
+
+// FindRequiredJavaModules runs the toolchain's jdeps over the given jars, returning the JDK modules they need
+func FindRequiredJavaModules(cfg project.ProjectConfig, jars []string) ([]string, error) {
+	javaHome, err := ResolveToolchainPath(cfg)
+	if err != nil {
+		return nil, err
+	}
+	multiRelease := "base"
+	if release, err := ReadJavaRelease(javaHome); err == nil && GetJavaFeatureVersion(release) != 0 {
+		multiRelease = strconv.Itoa(GetJavaFeatureVersion(release))
+	}
+	args := []string{"--print-module-deps", "--ignore-missing-deps", "--multi-release", multiRelease,
+		"--class-path", strings.Join(jars, string(os.PathListSeparator))}
+	cmd := exec.Command(javaHome+"/bin/jdeps", append(args, jars...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, errors.New(string(output))
+	}
+
+	// jdeps prints a single comma separated line, modules of the dependencies themselves aren't part of the JDK
+	provided := map[string]bool{}
+	for _, jar := range jars {
+		name, err := GetJarModuleName(jar, filepath.Base(jar))
+		if err == nil && name != "" {
+			provided[name] = true
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	modules := []string{}
+	for _, module := range strings.Split(lines[len(lines)-1], ",") {
+		module = strings.TrimSpace(module)
+		if module != "" && !provided[module] {
+			modules = append(modules, module)
+		}
+	}
+	return modules, nil
+}
+
+// LinkRuntime runs the toolchain's jlink to create a runtime image of the given modules (along with the configured
+// extra ones) in dist/runtime, replacing any previous one, then writes the application's launcher script into its
+// bin directory. Returns the path to the launcher.
+func LinkRuntime(cfg project.ProjectConfig, modules []string, options project.JlinkConfig) (string, error) {
+	javaHome, err := ResolveToolchainPath(cfg)
+	if err != nil {
+		return "", err
+	}
+	runtimePath, err := GetRuntimePath(cfg)
+	if err != nil {
+		return "", err
+	}
+	err = os.RemoveAll(runtimePath)
+	if err != nil {
+		return "", err
+	}
+
+	// java.base is always needed, the rest are deduplicated so the command is reproducible
+	unique := map[string]bool{"java.base": true}
+	for _, module := range append(modules, options.Modules...) {
+		unique[module] = true
+	}
+	addModules := []string{}
+	for module := range unique {
+		addModules = append(addModules, module)
+	}
+	sort.Strings(addModules)
+
+	// JDKs from 24 may be built without jmods, linking from their own runtime instead
+	args := []string{"--add-modules", strings.Join(addModules, ","), "--output", runtimePath, "--no-header-files", "--no-man-pages"}
+	jmodsPath := filepath.Join(javaHome, "jmods")
+	if exists, _ := util.DoesPathExist(jmodsPath); exists {
+		args = append([]string{"--module-path", jmodsPath}, args...)
+	}
+	if options.StripDebug {
+		args = append(args, "--strip-debug")
+	}
+	if options.Compress != "" {
+		args = append(args, "--compress="+options.Compress)
+	}
+	cmd := exec.Command(javaHome+"/bin/jlink", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.New(string(output))
+	}
+
+	// write the launcher, without replacing any of the runtime's own executables
+	launcherPath := filepath.Join(runtimePath, "bin", cfg.Name)
+	exists, err := util.DoesPathExist(launcherPath)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("the launcher '%s' would replace the runtime's own executable", launcherPath)
+	}
+	launcher := fmt.Sprintf(launcherTemplate, cfg.Name, GetArtifactName(cfg)+".jar")
+	err = os.WriteFile(launcherPath, []byte(launcher), 0755)
+	if err != nil {
+		return "", err
+	}
+	return launcherPath, nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package toolchain
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/source"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// PackageSources creates the "<name>-<version>-sources.jar" of the project's sources and resources, returning its path
+func PackageSources(cfg project.ProjectConfig) (string, error) {
+	distPath, err := GetDistPath(cfg)
+	if err != nil {
+		return "", err
+	}
+	jarPath := *distPath + "/" + GetArtifactName(cfg) + "-sources.jar"
+
+	// add the source roots that exist, and the resources directory if there is one
+	roots, err := source.GetSourceRoots(cfg)
+	if err != nil {
+		return "", err
+	}
+	resourcePath, err := source.GetResourcePath()
+	if err != nil {
+		return "", err
+	}
+	directories := []string{}
+	for _, path := range append(getSourceRootPaths(roots), resourcePath) {
+		exists, err := util.DoesPathExist(path)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			directories = append(directories, path)
+		}
+	}
+	err = WriteJar(jarPath, NewManifest().String(), directories)
+	if err != nil {
+		return "", err
+	}
+	return jarPath, nil
+}
+
+// PackageJavadoc generates the javadoc of the given source files with the toolchain's javadoc, then creates the
+// "<name>-<version>-javadoc.jar" of it, returning its path
+func PackageJavadoc(cfg project.ProjectConfig, files []source.SourceFile) (string, error) {
+	distPath, err := GetDistPath(cfg)
+	if err != nil {
+		return "", err
+	}
+	jarPath := *distPath + "/" + GetArtifactName(cfg) + "-javadoc.jar"
+
+	// generate the javadoc, it's kept out of the build directory so it doesn't end up within the jar
+	javadocPath, err := os.MkdirTemp("", "espresso-javadoc-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(javadocPath)
+	cpVal, err := GetClassPath(cfg)
+	if err != nil {
+		return "", err
+	}
+	javaHome, err := ResolveToolchainPath(cfg)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"-quiet", "-cp", cpVal, "-d", javadocPath}
+	for _, file := range files {
+		// documented against the classpath, so a module declaration would only get in the way
+		if filepath.Base(file.Path) != "module-info.java" {
+			args = append(args, file.Path)
+		}
+	}
+	cmd := exec.Command(javaHome+"/bin/javadoc", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.New(string(output))
+	}
+
+	// jar it
+	err = WriteJar(jarPath, NewManifest().String(), []string{javadocPath})
+	if err != nil {
+		return "", err
+	}
+	return jarPath, nil
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package toolchain
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"kerosenelabs.com/espresso/core/context/project"
+)
+
+// manifestAttributeNamePattern matches a valid attribute name per the JAR specification
+var manifestAttributeNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,69}$`)
+
+// ManifestAttribute is a single "Name: Value" attribute of a manifest
+type ManifestAttribute struct {
+	Name  string
+	Value string
+}
+
+// ManifestSection is a named section of a manifest, holding attributes for a particular entry of the jar
+type ManifestSection struct {
+	Name       string
+	Attributes []ManifestAttribute
+}
+
+// Manifest is a JAR manifest: its main attributes followed by its per-entry sections
+type Manifest struct {
+	Attributes []ManifestAttribute
+	Sections   []ManifestSection
+}
+
+// NewManifest creates a manifest with only the Manifest-Version and Created-By attributes
+func NewManifest() *Manifest {
+	manifest := &Manifest{}
+	manifest.Set("Manifest-Version", "1.0")
+	manifest.Set("Created-By", "Espresso")
+	return manifest
+}
+
+// Set sets a main attribute, replacing its value if it's already set (attribute names are case-insensitive)
+func (manifest *Manifest) Set(name string, value string) {
+	manifest.Attributes = setManifestAttribute(manifest.Attributes, name, value)
+}
+
+// SetSectionAttribute sets an attribute of the named section, creating the section if it doesn't exist
+func (manifest *Manifest) SetSectionAttribute(section string, name string, value string) {
+	for i := range manifest.Sections {
+		if manifest.Sections[i].Name == section {
+			manifest.Sections[i].Attributes = setManifestAttribute(manifest.Sections[i].Attributes, name, value)
+			return
+		}
+	}
+	manifest.Sections = append(manifest.Sections, ManifestSection{
+		Name:       section,
+		Attributes: []ManifestAttribute{{Name: name, Value: value}},
+	})
+}
+
+// Validate ensures every attribute name and value can be written, and that Manifest-Version comes first
+func (manifest *Manifest) Validate() error {
+	if len(manifest.Attributes) == 0 || manifest.Attributes[0].Name != "Manifest-Version" {
+		return fmt.Errorf("the manifest must start with Manifest-Version")
+	}
+	sections := [][]ManifestAttribute{manifest.Attributes}
+	for _, section := range manifest.Sections {
+		if err := validateManifestValue(section.Name); err != nil {
+			return fmt.Errorf("manifest section '%s': %w", section.Name, err)
+		}
+		sections = append(sections, section.Attributes)
+	}
+	for _, attributes := range sections {
+		for _, attribute := range attributes {
+			if !manifestAttributeNamePattern.MatchString(attribute.Name) {
+				return fmt.Errorf("'%s' is not a valid manifest attribute name", attribute.Name)
+			}
+			if err := validateManifestValue(attribute.Value); err != nil {
+				return fmt.Errorf("manifest attribute '%s': %w", attribute.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// String serializes the manifest, wrapping every line at 72 bytes
+func (manifest *Manifest) String() string {
+	var builder strings.Builder
+	writeManifestAttributes(&builder, manifest.Attributes)
+	for _, section := range manifest.Sections {
+		builder.WriteString("\n")
+		writeManifestAttributes(&builder, append([]ManifestAttribute{{Name: "Name", Value: section.Name}}, section.Attributes...))
+	}
+	return builder.String()
+}
+
+// ApplyManifestConfig sets the project's configured attributes and sections on the manifest, in name order so the
+// manifest is reproducible
+func ApplyManifestConfig(manifest *Manifest, cfg project.ManifestConfig) {
+	if cfg.MultiRelease {
+		manifest.Set("Multi-Release", "true")
+	}
+	for _, name := range getSortedKeys(cfg.Attributes) {
+		manifest.Set(name, cfg.Attributes[name])
+	}
+	for _, section := range cfg.Sections {
+		for _, name := range getSortedKeys(section.Attributes) {
+			manifest.SetSectionAttribute(section.Name, name, section.Attributes[name])
+		}
+	}
+}
+
+// setManifestAttribute sets an attribute within the list, replacing its value if it's already set
+func setManifestAttribute(attributes []ManifestAttribute, name string, value string) []ManifestAttribute {
+	for i := range attributes {
+		if strings.EqualFold(attributes[i].Name, name) {
+			attributes[i].Value = value
+			return attributes
+		}
+	}
+	return append(attributes, ManifestAttribute{Name: name, Value: value})
+}
+
+// validateManifestValue ensures a value has no characters a manifest can't hold
+func validateManifestValue(value string) error {
+	if !utf8.ValidString(value) {
+		return fmt.Errorf("the value is not valid UTF-8")
+	}
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return fmt.Errorf("the value must not contain line breaks or NUL characters")
+	}
+	return nil
+}
+
+// writeManifestAttributes writes each attribute as a wrapped line
+func writeManifestAttributes(builder *strings.Builder, attributes []ManifestAttribute) {
+	for _, attribute := range attributes {
+		for _, line := range capLinesAt72Bytes(attribute.Name + ": " + attribute.Value) {
+			builder.WriteString(line + "\n")
+		}
+	}
+}
+
+// getSortedKeys gets the keys of the map in order
+func getSortedKeys(values map[string]string) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// capLinesAt72Bytes wraps a manifest line so no line exceeds 72 bytes, continuation lines starting with a space as the
+// JAR specification requires. Multi-byte characters are never split across lines.
+func capLinesAt72Bytes(input string) []string {
+	var lines []string
+	var currentLine string
+
+	for len(input) > 0 {
+		_, size := utf8.DecodeRuneInString(input) // Get next rune and its byte size
+		if len(currentLine)+size > 72 {
+			lines = append(lines, currentLine) // Add current line to lines
+			currentLine = " "                  // Continuation lines start with a space
+		}
+		currentLine += input[:size] // Add the rune to the current line
+		input = input[size:]        // Move to the next part of the string
+	}
+
+	if currentLine != "" {
+		lines = append(lines, currentLine) // Add the last line if not empty
+	}
+
+	return lines
+}
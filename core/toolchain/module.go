@@ -0,0 +1,323 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package toolchain
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/dependency"
+	"kerosenelabs.com/espresso/core/source"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+var (
+	// automaticModuleVersionPattern matches the start of the version within a jar's file name
+	automaticModuleVersionPattern = regexp.MustCompile(`-(\d+(\.|$))`)
+	// moduleNameSeparatorPattern matches what's replaced by a dot when deriving an automatic module name
+	moduleNameSeparatorPattern = regexp.MustCompile(`[^A-Za-z0-9]+`)
+	// moduleNotReadPattern matches javac's error for a package within a module that isn't required
+	moduleNotReadPattern = regexp.MustCompile(`package ([\w.]+) is declared in module ([\w.]+), but module ([\w.]+) does not read it`)
+	// unnamedModulePattern matches javac's error for a package on the classpath rather than the module path
+	unnamedModulePattern = regexp.MustCompile(`package ([\w.]+) is declared in the unnamed module, but module ([\w.]+) does not read it`)
+	// moduleNotFoundPattern matches javac's error for a required module that isn't on the module path
+	moduleNotFoundPattern = regexp.MustCompile(`module not found: ([\w.]+)`)
+)
+
+// javaKeywords can't be part of a module name
+var javaKeywords = map[string]bool{
+	"abstract": true, "assert": true, "boolean": true, "break": true, "byte": true, "case": true, "catch": true,
+	"char": true, "class": true, "const": true, "continue": true, "default": true, "do": true, "double": true,
+	"else": true, "enum": true, "extends": true, "final": true, "finally": true, "float": true, "for": true,
+	"goto": true, "if": true, "implements": true, "import": true, "instanceof": true, "int": true, "interface": true,
+	"long": true, "native": true, "new": true, "package": true, "private": true, "protected": true, "public": true,
+	"return": true, "short": true, "static": true, "strictfp": true, "super": true, "switch": true,
+	"synchronized": true, "this": true, "throw": true, "throws": true, "transient": true, "try": true, "void": true,
+	"volatile": true, "while": true, "true": true, "false": true, "null": true, "_": true,
+}
+
+// ModuleDependency is a dependency of a modular project along with the module it provides
+type ModuleDependency struct {
+	Resolved dependency.ResolvedDependency
+	// FileName is the name of the jar on the module path, the same as within dist/libs
+	FileName string
+	// ModuleName is empty if the jar can't be used as a module, it's then put on the classpath
+	ModuleName string
+}
+
+// GetModuleDependencies gets the module provided by each of the project's dependencies
+func GetModuleDependencies(cfg project.ProjectConfig) ([]ModuleDependency, error) {
+	resolvedDependencies, err := dependency.ResolveProjectDependencies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	modules := []ModuleDependency{}
+	providers := map[string]dependency.ResolvedDependency{}
+	for _, resolved := range resolvedDependencies {
+		jarPath, err := resolved.GetCachePath()
+		if err != nil {
+			return nil, err
+		}
+		fileName := resolved.GetLibFileName()
+		moduleName, err := GetJarModuleName(jarPath.Absolute, fileName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the module of '%s:%s': %w", resolved.Package.Group, resolved.Package.Name, err)
+		}
+
+		// the module path can only hold one module of each name, javac would silently pick one
+		if provider, ok := providers[moduleName]; ok && moduleName != "" {
+			return nil, fmt.Errorf("both '%s:%s' and '%s:%s' provide the module '%s'", provider.Package.Group, provider.Package.Name, resolved.Package.Group, resolved.Package.Name, moduleName)
+		}
+		providers[moduleName] = resolved
+		modules = append(modules, ModuleDependency{Resolved: resolved, FileName: fileName, ModuleName: moduleName})
+	}
+	return modules, nil
+}
+
+// GetJarModuleName gets the name of the module a jar provides: the module its module-info.class declares, otherwise
+// its Automatic-Module-Name, otherwise the name derived from the given file name as the JDK would. Returns an empty
+// string if no valid name can be derived.
+func GetJarModuleName(jarPath string, fileName string) (string, error) {
+	reader, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	// an explicit module, possibly only within a multi-release jar's versioned entries
+	for _, file := range reader.File {
+		matched, _ := path.Match("META-INF/versions/*/module-info.class", file.Name)
+		if file.Name != "module-info.class" && !matched {
+			continue
+		}
+		content, err := readZipFile(file)
+		if err != nil {
+			return "", err
+		}
+		class, err := parseClassFile(content)
+		if err != nil {
+			return "", err
+		}
+		return class.getModuleName()
+	}
+
+	// an automatic module
+	for _, file := range reader.File {
+		if file.Name != "META-INF/MANIFEST.MF" {
+			continue
+		}
+		content, err := readZipFile(file)
+		if err != nil {
+			return "", err
+		}
+		if name := getManifestMainAttribute(string(content), "Automatic-Module-Name"); name != "" {
+			return name, nil
+		}
+	}
+	return DeriveAutomaticModuleName(fileName), nil
+}
+
+// DeriveAutomaticModuleName derives a module name from a jar's file name as java.lang.module.ModuleFinder does,
+// returning an empty string if the result isn't a valid module name
+func DeriveAutomaticModuleName(fileName string) string {
+	name := strings.TrimSuffix(fileName, ".jar")
+	if location := automaticModuleVersionPattern.FindStringIndex(name); location != nil {
+		name = name[:location[0]]
+	}
+	name = strings.Trim(moduleNameSeparatorPattern.ReplaceAllString(name, "."), ".")
+	if name == "" {
+		return ""
+	}
+	for _, part := range strings.Split(name, ".") {
+		if javaKeywords[part] || (part[0] >= '0' && part[0] <= '9') {
+			return ""
+		}
+	}
+	return name
+}
+
+// CompileModule compiles every source file of a modular project in a single invocation of javac, with its dependencies
+// on the module path. Errors caused by a missing "requires" are annotated with the dependency providing the module.
+func CompileModule(cfg project.ProjectConfig, files []source.SourceFile, declaration *source.ModuleDeclaration) error {
+	modules, err := GetModuleDependencies(cfg)
+	if err != nil {
+		return err
+	}
+
+	// stage the dependencies under their dist/libs names, javac derives automatic module names from file names. Each
+	// group gets its own directory so packages of the same name from different groups don't overwrite one another.
+	stagingPath, err := os.MkdirTemp("", "espresso-modules-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingPath)
+	modulePath := []string{}
+	classPath := []string{}
+	for _, module := range modules {
+		jarPath, err := module.Resolved.GetCachePath()
+		if err != nil {
+			return err
+		}
+		if module.ModuleName == "" {
+			classPath = append(classPath, jarPath.Absolute)
+			continue
+		}
+		groupPath := filepath.Join(stagingPath, module.Resolved.Package.Group)
+		if !slices.Contains(modulePath, groupPath) {
+			err = os.MkdirAll(groupPath, 0755)
+			if err != nil {
+				return err
+			}
+			modulePath = append(modulePath, groupPath)
+		}
+		err = linkOrCopyFile(jarPath.Absolute, filepath.Join(groupPath, module.FileName))
+		if err != nil {
+			return err
+		}
+	}
+
+	// run the compiler
+	javaHome, err := ResolveToolchainPath(cfg)
+	if err != nil {
+		return err
+	}
+	buildPath, err := GetBuildPath(cfg)
+	if err != nil {
+		return err
+	}
+	args := []string{"-d", *buildPath}
+	if len(modulePath) > 0 {
+		args = append(args, "--module-path", strings.Join(modulePath, string(os.PathListSeparator)))
+	}
+	if len(classPath) > 0 {
+		args = append(args, "-cp", strings.Join(classPath, string(os.PathListSeparator)))
+	}
+	for _, file := range files {
+		args = append(args, file.Path)
+	}
+	cmd := exec.Command(javaHome+"/bin/javac", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.New(string(output) + describeModuleErrors(string(output), declaration, modules))
+	}
+	return nil
+}
+
+// SetModuleMainClass sets the ModuleMainClass attribute of the compiled module-info.class, if the project is modular,
+// so "java --module <name>" runs the given class
+func SetModuleMainClass(cfg project.ProjectConfig, mainClass string) error {
+	buildPath, err := GetBuildPath(cfg)
+	if err != nil {
+		return err
+	}
+	moduleInfoPath := filepath.Join(*buildPath, "module-info.class")
+	content, err := os.ReadFile(moduleInfoPath)
+	if errors.Is(err, os.ErrNotExist) || mainClass == "" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	class, err := parseClassFile(content)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(moduleInfoPath, class.setModuleMainClass(mainClass), 0644)
+}
+
+// describeModuleErrors maps javac's module errors back to the dependencies that would resolve them
+func describeModuleErrors(output string, declaration *source.ModuleDeclaration, modules []ModuleDependency) string {
+	byModule := map[string]ModuleDependency{}
+	for _, module := range modules {
+		if module.ModuleName != "" {
+			byModule[module.ModuleName] = module
+		}
+	}
+	coordinate := func(module ModuleDependency) string {
+		return module.Resolved.Package.Group + ":" + module.Resolved.Package.Name + ":" + module.Resolved.PackageVersion.Number
+	}
+
+	hints := map[string]bool{}
+	for _, match := range moduleNotReadPattern.FindAllStringSubmatch(output, -1) {
+		hint := fmt.Sprintf("add 'requires %s;' to %s to use package '%s'", match[2], declaration.File.Path, match[1])
+		if module, ok := byModule[match[2]]; ok {
+			hint += fmt.Sprintf(" (provided by the dependency '%s')", coordinate(module))
+		}
+		hints[hint] = true
+	}
+	for _, match := range unnamedModulePattern.FindAllStringSubmatch(output, -1) {
+		hints[fmt.Sprintf("package '%s' is on the classpath as its jar has no valid module name, it can't be required by a module", match[1])] = true
+	}
+	for _, match := range moduleNotFoundPattern.FindAllStringSubmatch(output, -1) {
+		available := []string{}
+		for name := range byModule {
+			available = append(available, name)
+		}
+		sort.Strings(available)
+		hints[fmt.Sprintf("no dependency provides the module '%s' (dependencies provide: %s)", match[1], strings.Join(available, ", "))] = true
+	}
+	if len(hints) == 0 {
+		return ""
+	}
+
+	lines := []string{}
+	for hint := range hints {
+		lines = append(lines, "hint: "+hint)
+	}
+	sort.Strings(lines)
+	return "\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// getManifestMainAttribute gets the value of an attribute within a manifest's main section, or an empty string
+func getManifestMainAttribute(manifest string, name string) string {
+	// unwrap continuation lines, the main section ends at the first blank line
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(manifest, "\r\n", "\n"), "\n") {
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, " ") && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	for _, line := range lines {
+		attribute, value, found := strings.Cut(line, ": ")
+		if found && strings.EqualFold(attribute, name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// readZipFile reads the contents of a file within a zip
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// linkOrCopyFile hard links the file to the destination, copying it if it can't be linked (ex: across devices)
+func linkOrCopyFile(src string, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return util.CopyFile(src, dst)
+}
@@ -5,126 +5,82 @@
 package toolchain
 
 import (
-	"errors"
 	"os"
-	"os/exec"
-	"unicode/utf8"
+	"path/filepath"
+	"strings"
 
 	"kerosenelabs.com/espresso/core/context/project"
 	"kerosenelabs.com/espresso/core/dependency"
-	"kerosenelabs.com/espresso/core/util"
 )
 
-func capLinesAt72Bytes(input string) []string {
-	var lines []string
-	var currentLine string
-	var currentLineBytes int
-
-	for len(input) > 0 {
-		r, size := utf8.DecodeRuneInString(input) // Get next rune and its byte size
-		if currentLineBytes+size > 72 {
-			lines = append(lines, currentLine) // Add current line to lines
-			currentLine = ""                   // Reset current line
-			currentLineBytes = 0               // Reset byte counter
-		}
-		currentLine += string(r) // Add the rune to the current line
-		currentLineBytes += size // Track the byte size
-		input = input[size:]     // Move to the next part of the string
+// GenerateManifest generates the JVM manifest of the project's jar. The project's configured attributes are applied
+// last so they may override any generated attribute.
+func GenerateManifest(cfg project.ProjectConfig) (string, error) {
+	manifest := NewManifest()
+	mainClass, err := ResolveMainClass(cfg)
+	if err != nil {
+		return "", err
 	}
-
-	if currentLineBytes > 0 {
-		lines = append(lines, " "+currentLine) // Add the last line if not empty
+	if mainClass != "" {
+		manifest.Set("Main-Class", mainClass)
 	}
+	manifest.Set("Implementation-Title", cfg.Name)
+	manifest.Set("Implementation-Version", project.GetVersionAsString(cfg.Version))
 
-	return lines
-}
-
-// GenerateManifest generates a JVM manifest
-func GenerateManifest(cfg project.ProjectConfig) (string, error) {
-	base := "Manifest-Version: 1.0\n"
-	base += "Main-Class: " + cfg.BasePackage + ".Main\n"
-	base += "Created-By: Espresso\n"
-
-	// iterate over dependencies, resolve them and add them to the manifest base
-	classPath := "Class-Path: "
-	for _, dep := range cfg.Dependencies {
-		resolvedDependency, err := dependency.ResolveDependency(dep, cfg.Registries)
+	// libraries leave their dependencies to whoever depends on them
+	if !cfg.IsLibrary() {
+		resolvedDependencies, err := dependency.ResolveProjectDependencies(cfg)
 		if err != nil {
 			return "", err
 		}
-		classPath += "libs/" + resolvedDependency.Package.Name + ".jar "
+		classPath := []string{}
+		for _, resolvedDependency := range resolvedDependencies {
+			classPath = append(classPath, "libs/"+resolvedDependency.GetLibFileName())
+		}
+		if len(classPath) > 0 {
+			manifest.Set("Class-Path", strings.Join(classPath, " "))
+		}
 	}
 
-	splitLines := capLinesAt72Bytes(classPath)
-	for _, line := range splitLines {
-		base += line + "\n"
+	ApplyManifestConfig(manifest, cfg.Manifest)
+	err = manifest.Validate()
+	if err != nil {
+		return "", err
 	}
-
-	return base, nil
+	return manifest.String(), nil
 }
 
-// Write the Manifest to the build directory
-func WriteManifest(cfg project.ProjectConfig) error {
-	// get the path where it should live
+// PackageClasses creates the project's jar of its compiled classes and resources
+func PackageClasses(cfg project.ProjectConfig) error {
 	buildPath, err := GetBuildPath(cfg)
-	path := *buildPath + "/MANIFEST.MF"
 	if err != nil {
 		return err
 	}
 
-	// open the file
-	file, err := os.Create(path)
+	// handle jar output path
+	jarPath, err := GetJarPath(cfg)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	// write the file
-	content, err := GenerateManifest(cfg)
+	err = os.MkdirAll(filepath.Dir(jarPath), 0755)
 	if err != nil {
 		return err
 	}
-	_, err = file.WriteString(content)
+
+	// modular jars record their main class within module-info.class
+	mainClass, err := ResolveMainClass(cfg)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-// PackageClasses creates a .jar of the given classes
-func PackageClasses(cfg project.ProjectConfig) error {
-	command := cfg.Toolchain.Path + "/bin/jar"
-	args := []string{"cfm"}
-
-	// handle jar output path
-	if util.IsDebugMode() {
-		args = append(args, "ESPRESSO_DEBUG/dist/dist.jar")
-	} else {
-		args = append(args, "dist/dist.jar")
-	}
-
-	// write the manifest, include it
-	WriteManifest(cfg)
-	if util.IsDebugMode() {
-		args = append(args, "ESPRESSO_DEBUG/build/MANIFEST.MF")
-	} else {
-		args = append(args, "build/MANIFEST.MF")
-	}
-
-	// add the class directory
-	if util.IsDebugMode() {
-		args = append(args, "-C", "ESPRESSO_DEBUG/build")
-	} else {
-		args = append(args, "-C", "build")
+	err = SetModuleMainClass(cfg, mainClass)
+	if err != nil {
+		return err
 	}
-	args = append(args, ".")
 
-	// run the command
-	cmd := exec.Command(command, args...)
-	output, err := cmd.CombinedOutput()
+	// generate the manifest, write the jar
+	manifest, err := GenerateManifest(cfg)
 	if err != nil {
-		return errors.New(string(output))
+		return err
 	}
-
-	return nil
+	return WriteJar(jarPath, manifest, []string{*buildPath})
 }
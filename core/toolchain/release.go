@@ -0,0 +1,73 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package toolchain
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReadJavaRelease reads the KEY="value" pairs of a JDK's release file
+func ReadJavaRelease(javaHome string) (map[string]string, error) {
+	file, err := os.Open(filepath.Join(javaHome, "release"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	release := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if found {
+			release[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return release, scanner.Err()
+}
+
+// GetJavaFeatureVersion gets the feature version (ex: 21 of "21.0.2", 8 of "1.8.0_392") of a release's JAVA_VERSION,
+// or 0 if it can't be read
+func GetJavaFeatureVersion(release map[string]string) int {
+	parts := getJavaVersionParts(release["JAVA_VERSION"])
+	if len(parts) == 0 {
+		return 0
+	}
+	return parts[0]
+}
+
+// compareJavaVersions compares two JAVA_VERSIONs numerically, returning a positive number if a is newer than b
+func compareJavaVersions(a string, b string) int {
+	aParts, bParts := getJavaVersionParts(a), getJavaVersionParts(b)
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart int
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		if aPart != bPart {
+			return aPart - bPart
+		}
+	}
+	return 0
+}
+
+// getJavaVersionParts gets the numbers of a JAVA_VERSION, dropping the "1." prefix of Java 8 and earlier
+func getJavaVersionParts(version string) []int {
+	version = strings.TrimPrefix(version, "1.")
+	parts := []int{}
+	for _, field := range strings.FieldsFunc(version, func(r rune) bool { return r < '0' || r > '9' }) {
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, number)
+	}
+	return parts
+}
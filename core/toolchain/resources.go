@@ -0,0 +1,55 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package toolchain
+
+import (
+	"os"
+	"path/filepath"
+
+	"kerosenelabs.com/espresso/core/context/project"
+	"kerosenelabs.com/espresso/core/source"
+	"kerosenelabs.com/espresso/core/util"
+)
+
+// CopyResources copies the project's resources into the build directory, alongside the compiled classes, so they're
+// packaged into the jar. Resources matching the project's filter patterns have their placeholders substituted.
+// Returns the resources that were copied.
+func CopyResources(cfg project.ProjectConfig) ([]source.ResourceFile, error) {
+	buildPath, err := GetBuildPath(cfg)
+	if err != nil {
+		return nil, err
+	}
+	files, err := source.DiscoverResourceFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	values := source.GetFilterValues(cfg)
+	for _, file := range files {
+		destination := filepath.Join(*buildPath, filepath.FromSlash(file.RelativePath))
+		err = os.MkdirAll(filepath.Dir(destination), 0755)
+		if err != nil {
+			return nil, err
+		}
+		if !source.ShouldFilterResource(cfg, file) {
+			err = util.CopyFile(file.Path, destination)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// substitute the placeholders
+		content, err := os.ReadFile(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		err = os.WriteFile(destination, []byte(source.FilterResource(string(content), values)), 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
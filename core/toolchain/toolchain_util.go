@@ -42,3 +42,20 @@ func GetDistPath(cfg project.ProjectConfig) (*string, error) {
 	path += "/dist"
 	return &path, nil
 }
+
+// GetArtifactName gets the base name of the project's jars, "<name>-<version>" for libraries
+func GetArtifactName(cfg project.ProjectConfig) string {
+	if cfg.IsLibrary() {
+		return cfg.Name + "-" + project.GetVersionAsString(cfg.Version)
+	}
+	return "dist"
+}
+
+// GetJarPath gets the absolute path to the project's jar within the dist directory
+func GetJarPath(cfg project.ProjectConfig) (string, error) {
+	distPath, err := GetDistPath(cfg)
+	if err != nil {
+		return "", err
+	}
+	return *distPath + "/" + GetArtifactName(cfg) + ".jar", nil
+}
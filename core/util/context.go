@@ -19,6 +19,58 @@ func IsDebugMode() bool {
 	return val == "1"
 }
 
+// offlineMode is set by the --offline flag, nil until it's forced either way, see IsOfflineMode
+var offlineMode *bool
+
+// SetOfflineMode forces offline mode on or off for the rest of this process, overriding ESPRESSO_OFFLINE.
+func SetOfflineMode(offline bool) {
+	offlineMode = &offline
+}
+
+// IsOfflineMode returns if we must never touch the network, either via the --offline flag or "ESPRESSO_OFFLINE=1".
+// Resolution then only uses cached registries and packages, and any attempted download is an error.
+func IsOfflineMode() bool {
+	if offlineMode != nil {
+		return *offlineMode
+	}
+	val, present := os.LookupEnv("ESPRESSO_OFFLINE")
+	if !present {
+		return false
+	}
+	return val == "1" || val == "true"
+}
+
+// vendorMode is set by the --vendor flag, nil until it's forced either way, see IsVendorMode
+var vendorMode *bool
+
+// SetVendorMode forces vendor mode on or off for the rest of this process, overriding ESPRESSO_VENDOR.
+func SetVendorMode(vendor bool) {
+	vendorMode = &vendor
+}
+
+// IsVendorMode returns if dependencies must only be resolved from the project's vendor directory, either via the
+// --vendor flag or "ESPRESSO_VENDOR=1".
+func IsVendorMode() bool {
+	if vendorMode != nil {
+		return *vendorMode
+	}
+	val, present := os.LookupEnv("ESPRESSO_VENDOR")
+	if !present {
+		return false
+	}
+	return val == "1" || val == "true"
+}
+
+// IsLocalCacheMode returns if "ESPRESSO_LOCAL_CACHE=1" asks for the project-local cache to be used regardless of the
+// project's localCache setting.
+func IsLocalCacheMode() bool {
+	val, present := os.LookupEnv("ESPRESSO_LOCAL_CACHE")
+	if !present {
+		return false
+	}
+	return val == "1" || val == "true"
+}
+
 // GetJavaHome gets the value of the JAVA_HOME
 func GetJavaHome() (*string, error) {
 	path, present := os.LookupEnv("JAVA_HOME")
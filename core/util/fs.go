@@ -90,3 +90,42 @@ func CopyFile(src, dst string) error {
 
 	return nil
 }
+
+// WriteFileAtomic writes the data to a temporary file next to the given path and renames it into place, so readers
+// never see a partially written file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return WriteAtomic(path, perm, func(writer io.Writer) error {
+		_, err := writer.Write(data)
+		return err
+	})
+}
+
+// WriteAtomic is WriteFileAtomic for content too large to hold in memory, streaming whatever the write function
+// writes into the temporary file.
+func WriteAtomic(path string, perm os.FileMode, write func(writer io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = write(tmp)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), perm)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
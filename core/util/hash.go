@@ -5,8 +5,13 @@
 package util
 
 import (
+	"crypto/sha1"
 	"crypto/sha256"
 	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
 )
 
 // GetFileChecksum gets the SHA-256 checksum of a file at a given directory.
@@ -14,3 +19,50 @@ func GetChecksum(content string) (string, error) {
 	hash := sha256.Sum256([]byte(content))
 	return fmt.Sprintf("%x", hash), nil
 }
+
+// HashFile gets the hex encoded digest of the file at the given path using the given algorithm ("sha256" or "sha1").
+func HashFile(path string, algorithm string) (string, error) {
+	var h hash.Hash
+	switch algorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha1":
+		h = sha1.New()
+	default:
+		return "", fmt.Errorf("unsupported checksum algorithm '%s'", algorithm)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// ParseChecksum splits a checksum in the "<algorithm>:<hex digest>" format. A bare digest is assumed to be SHA-256.
+func ParseChecksum(checksum string) (string, string) {
+	algorithm, digest, found := strings.Cut(checksum, ":")
+	if !found {
+		return "sha256", strings.ToLower(checksum)
+	}
+	return strings.ToLower(algorithm), strings.ToLower(digest)
+}
+
+// VerifyFileChecksum ensures the file at the given path matches the given "<algorithm>:<hex digest>" checksum.
+func VerifyFileChecksum(path string, checksum string) error {
+	algorithm, expected := ParseChecksum(checksum)
+	actual, err := HashFile(path, algorithm)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("checksum mismatch for '%s': expected %s:%s, got %s:%s", path, algorithm, expected, algorithm, actual)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Kerosene Labs
+// This file is part of Espresso, which is licensed under the MIT License.
+// See the LICENSE file for details.
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// FileLock is an advisory lock held on a file, shared between every Espresso process on the machine. Lock files are
+// never removed, as removing one while another process waits on it would let a third process lock a new file at the
+// same path.
+type FileLock struct {
+	file *os.File
+}
+
+// LockFile blocks until an exclusive lock is acquired on the file at the given path, creating it if needed.
+func LockFile(path string) (*FileLock, error) {
+	return acquireFileLock(path, true)
+}
+
+// RLockFile blocks until a shared lock is acquired on the file at the given path, creating it if needed. Any amount
+// of shared locks may be held at once, but never alongside an exclusive lock.
+func RLockFile(path string) (*FileLock, error) {
+	return acquireFileLock(path, false)
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	err := unlockFile(l.file)
+	closeErr := l.file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// acquireFileLock opens the lock file and blocks until the lock is acquired
+func acquireFileLock(path string, exclusive bool) (*FileLock, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	err = lockFile(file, exclusive)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &FileLock{file: file}, nil
+}
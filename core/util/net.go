@@ -5,36 +5,248 @@
 package util
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// DownloadOptions configures how a file is downloaded.
+type DownloadOptions struct {
+	// Retries is the amount of times a failed download is retried before giving up
+	Retries int
+	// InitialBackoff is the delay before the first retry, doubled on each subsequent retry
+	InitialBackoff time.Duration
+	// MaxBytes is the largest body we'll accept, zero means unlimited
+	MaxBytes int64
+	// Checksum, if set, is the "<algorithm>:<hex digest>" the downloaded file must match before it is moved into place
+	Checksum string
+}
+
+// DefaultDownloadOptions are the options used by DownloadFile
+var DefaultDownloadOptions = DownloadOptions{
+	Retries:        3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBytes:       0,
+}
+
+// HTTPStatusError is returned when a server responds with an unexpected status code.
+type HTTPStatusError struct {
+	Url        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status '%d %s' from '%s'", e.StatusCode, http.StatusText(e.StatusCode), e.Url)
+}
+
+// ErrOffline is returned when a download is attempted in offline mode
+var ErrOffline = errors.New("network access is disabled in offline mode")
+
+// errBodyTooLarge is returned when a response body exceeds DownloadOptions.MaxBytes
+var errBodyTooLarge = errors.New("response body exceeds the maximum allowed size")
+
+// httpClient is the shared client for all of Espresso's network access. It honors HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+var httpClient = &http.Client{
+	Timeout: 10 * time.Minute,
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   15 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          16,
+	},
+}
+
+// DownloadFile downloads the given url to the given filepath using DefaultDownloadOptions.
 func DownloadFile(filepath string, url string) error {
-	// Create the file
-	out, err := os.Create(filepath)
+	return DownloadFileWithOptions(filepath, url, DefaultDownloadOptions)
+}
+
+// DownloadFileWithOptions downloads the given url to the given path. The body is written to a temporary file next
+// to the destination which is only renamed into place once it has been fully written, so a failed download never
+// leaves a truncated file at the destination. Transient failures are retried with exponential backoff, resuming
+// from the bytes already written when the server supports range requests.
+func DownloadFileWithOptions(path string, url string, opts DownloadOptions) error {
+	if IsOfflineMode() {
+		return fmt.Errorf("unable to download '%s': %w", url, ErrOffline)
+	}
+
+	// create our temp file next to the destination so the rename stays on the same filesystem
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0755)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	defer tmp.Close()
+
+	// attempt the download, retrying on transient errors
+	backoff := opts.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err = downloadAttempt(tmp, url, opts)
+		if err == nil {
+			break
+		}
+		if attempt >= opts.Retries || !isRetryable(err) {
+			return fmt.Errorf("download failed after %d attempt(s): %w", attempt+1, err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
 
-	// Get the data
-	resp, err := http.Get(url)
+	// flush it
+	err = tmp.Sync()
+	if err != nil {
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	// verify it, move it into place
+	if opts.Checksum != "" {
+		err = VerifyFileChecksum(tmpPath, opts.Checksum)
+		if err != nil {
+			return fmt.Errorf("'%s' failed verification: %w", url, err)
+		}
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// downloadAttempt performs a single request, writing into out. If out already contains bytes from a previous
+// attempt, a range request is made to resume from where it left off.
+func downloadAttempt(out *os.File, url string, opts DownloadOptions) error {
+	// figure out how much we already have
+	offset, err := out.Seek(0, io.SeekEnd)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	// Check server response
-	if resp.StatusCode != http.StatusOK {
+	// build the request
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
 		return err
 	}
+	req.Header.Set("User-Agent", "espresso")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
+	// get the data
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
+	// check server response
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		// resuming, unless the server sent a different range than we asked for
+		start, ok := getContentRangeStart(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return restartDownload(out, url, opts)
+		}
+	case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// what we have is stale or already complete, we can't tell which
+		return restartDownload(out, url, opts)
+	case resp.StatusCode == http.StatusOK:
+		// the server sent the whole body, start over
+		err = truncateDownload(out)
+		if err != nil {
+			return err
+		}
+		offset = 0
+	default:
+		return &HTTPStatusError{Url: url, StatusCode: resp.StatusCode}
+	}
+
+	// enforce our size limit
+	body := io.Reader(resp.Body)
+	if opts.MaxBytes > 0 {
+		if resp.ContentLength > 0 && offset+resp.ContentLength > opts.MaxBytes {
+			return errBodyTooLarge
+		}
+		body = io.LimitReader(resp.Body, opts.MaxBytes-offset+1)
+	}
 
+	// write the body to file
+	written, err := io.Copy(out, body)
+	if err != nil {
+		return err
+	}
+	if opts.MaxBytes > 0 && offset+written > opts.MaxBytes {
+		return errBodyTooLarge
+	}
+	if resp.ContentLength > 0 && written != resp.ContentLength {
+		return io.ErrUnexpectedEOF
+	}
 	return nil
 }
+
+// restartDownload discards what a previous attempt wrote and downloads the whole body again, without a range
+func restartDownload(out *os.File, url string, opts DownloadOptions) error {
+	err := truncateDownload(out)
+	if err != nil {
+		return err
+	}
+	return downloadAttempt(out, url, opts)
+}
+
+// truncateDownload empties the file so it's written from its start
+func truncateDownload(out *os.File) error {
+	err := out.Truncate(0)
+	if err != nil {
+		return err
+	}
+	_, err = out.Seek(0, io.SeekStart)
+	return err
+}
+
+// getContentRangeStart gets the first byte position of a "bytes <start>-<end>/<size>" Content-Range header
+func getContentRangeStart(contentRange string) (int64, bool) {
+	rangeSpec, ok := strings.CutPrefix(strings.TrimSpace(contentRange), "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(rangeSpec, "-")
+	if !ok {
+		return 0, false
+	}
+	position, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return position, true
+}
+
+// isRetryable returns if the given download error is worth retrying.
+func isRetryable(err error) bool {
+	if errors.Is(err, errBodyTooLarge) || errors.Is(err, ErrOffline) {
+		return false
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode == http.StatusRequestTimeout ||
+			statusErr.StatusCode >= 500
+	}
+	return true
+}
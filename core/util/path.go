@@ -4,7 +4,12 @@
 
 package util
 
-import "os/user"
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+)
 
 type Path struct {
 	Absolute string
@@ -19,11 +24,82 @@ func (p Path) DoesExist() (bool, error) {
 	return exists, nil
 }
 
-// GetEspressoDirectoryPath gets the path to the user's .espresso directory.
+// projectCachePath is set when the current project keeps its own cache, see SetProjectCachePath
+var projectCachePath string
+
+// SetProjectCachePath makes GetEspressoCachePath return the given project-local directory for the rest of this process.
+func SetProjectCachePath(path string) {
+	projectCachePath = path
+}
+
+// GetEspressoDirectoryPath gets the path to the user's Espresso home directory. In order of precedence, this is
+// $ESPRESSO_HOME, an existing ~/.espresso, $XDG_DATA_HOME/espresso, then ~/.espresso.
 func GetEspressoDirectoryPath() (string, error) {
-	user, err := user.Current()
-	if err != nil {
-		return "", err
+	if home, present := os.LookupEnv("ESPRESSO_HOME"); present && home != "" {
+		return filepath.Abs(home)
+	}
+
+	// prefer the legacy location if it's already in use
+	homeDir, homeErr := getUserHomeDirectory()
+	if homeErr == nil {
+		exists, err := DoesPathExist(homeDir + "/.espresso")
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return homeDir + "/.espresso", nil
+		}
+	}
+
+	if dataHome, present := os.LookupEnv("XDG_DATA_HOME"); present && filepath.IsAbs(dataHome) {
+		return filepath.Join(dataHome, "espresso"), nil
+	}
+	if homeErr != nil {
+		return "", homeErr
+	}
+	return homeDir + "/.espresso", nil
+}
+
+// GetEspressoCachePath gets the path to the directory holding the registry and package caches. In order of precedence,
+// this is $ESPRESSO_CACHE_DIR, the project-local cache (if enabled), $ESPRESSO_HOME, $XDG_CACHE_HOME/espresso (unless
+// ~/.espresso is already in use), then the Espresso home directory.
+func GetEspressoCachePath() (string, error) {
+	if cacheDir, present := os.LookupEnv("ESPRESSO_CACHE_DIR"); present && cacheDir != "" {
+		return filepath.Abs(cacheDir)
+	}
+	if projectCachePath != "" {
+		return projectCachePath, nil
+	}
+	if _, present := os.LookupEnv("ESPRESSO_HOME"); present {
+		return GetEspressoDirectoryPath()
+	}
+
+	// use the XDG cache directory unless the legacy location is already in use
+	if cacheHome, present := os.LookupEnv("XDG_CACHE_HOME"); present && filepath.IsAbs(cacheHome) {
+		homeDir, err := getUserHomeDirectory()
+		legacyExists := false
+		if err == nil {
+			legacyExists, err = DoesPathExist(homeDir + "/.espresso")
+			if err != nil {
+				return "", err
+			}
+		}
+		if !legacyExists {
+			return filepath.Join(cacheHome, "espresso"), nil
+		}
+	}
+	return GetEspressoDirectoryPath()
+}
+
+// getUserHomeDirectory gets the user's home directory, preferring $HOME over the user database as containers often run
+// as users without an entry in it.
+func getUserHomeDirectory() (string, error) {
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		return home, nil
+	}
+	current, err := user.Current()
+	if err == nil && current.HomeDir != "" {
+		return current.HomeDir, nil
 	}
-	return user.HomeDir + "/.espresso", nil
+	return "", errors.New("unable to determine the user's home directory, please set ESPRESSO_HOME")
 }
@@ -3,6 +3,8 @@ package main
 import (
 	"github.com/spf13/cobra"
 	"kerosenelabs.com/espresso/cli"
+	"kerosenelabs.com/espresso/core/service"
+	"kerosenelabs.com/espresso/core/util"
 )
 
 var CommitSha string
@@ -12,7 +14,15 @@ func main() {
 	var root = &cobra.Command{
 		Use:   "espresso",
 		Short: "A modern Java build tool.",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			var offline, _ = cmd.Flags().GetBool("offline")
+			if cmd.Flags().Changed("offline") {
+				util.SetOfflineMode(offline)
+			}
+			service.WarnLegacyToolchain()
+		},
 	}
+	root.PersistentFlags().Bool("offline", false, "Never access the network, only use cached registries and packages (or set ESPRESSO_OFFLINE=1)")
 
 	root.AddCommand(cli.GetVersionCommand())
 	root.AddCommand(cli.GetCleanCommand())
@@ -20,6 +30,11 @@ func main() {
 	root.AddCommand(cli.GetInitCommand())
 	root.AddCommand(cli.GetRegistryCommand())
 	root.AddCommand(cli.GetDependencyCommand())
+	root.AddCommand(cli.GetCacheCommand())
+	root.AddCommand(cli.GetVendorCommand())
+	root.AddCommand(cli.GetJlinkCommand())
+	root.AddCommand(cli.GetDistCommand())
+	root.AddCommand(cli.GetToolchainCommand())
 
 	// execute
 	root.Execute()